@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/wdt/internal/data"
+	validator "github.com/wdt/internal/validators"
+)
+
+// visitToolHandler counts an outbound click and redirects to the tool's
+// website.
+func (app *application) visitToolHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	tool, err := app.models.Tools.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	website, err := url.Parse(tool.Website)
+	if !tool.Published || err != nil || (website.Scheme != "http" && website.Scheme != "https") {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	app.analytics.RecordClick(tool.ID)
+
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, website.String(), http.StatusFound)
+}
+
+func (app *application) getAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	filter := data.AnalyticsFilter{
+		GroupBy:  app.readString(qs, "groupBy", data.AnalyticsByTool),
+		From:     app.readDate(qs, "from", today.AddDate(0, 0, -29), v),
+		To:       app.readDate(qs, "to", today, v),
+		ToolID:   int64(app.readInt(qs, "toolId", 0, v)),
+		Category: app.readString(qs, "category", ""),
+		Limit:    app.readInt(qs, "limit", 20, v),
+	}
+
+	if data.ValidateAnalyticsFilter(v, filter); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	series, err := app.models.Analytics.Series(filter)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"series": series,
+		"from":   filter.From.Format("2006-01-02"),
+		"to":     filter.To.Format("2006-01-02"),
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
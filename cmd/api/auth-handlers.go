@@ -5,11 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"net"
 	"net/http"
+	"net/netip"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/wdt/internal/challenge"
 	"github.com/wdt/internal/data"
 	"github.com/wdt/internal/tokens"
 	validator "github.com/wdt/internal/validators"
@@ -17,6 +20,26 @@ import (
 )
 
 
+func (app *application) challengeHandler(w http.ResponseWriter, r *http.Request) {
+	c := &challenge.Challenge{Type: "none"}
+	if issuer, ok := app.verifier.(challenge.Issuer); ok {
+		var err error
+		c, err = issuer.Issue()
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"challenge": c}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// registerUserWithMagicLinkHandler mails a magic link. No user is created
+// here; that waits until the link is clicked, so requests for addresses
+// nobody owns leave nothing behind.
 func (app *application) registerUserWithMagicLinkHandler(w http.ResponseWriter, r *http.Request) {
 	session := app.contextGetUser(r)
 
@@ -27,17 +50,15 @@ func (app *application) registerUserWithMagicLinkHandler(w http.ResponseWriter,
 
 	var input struct {
 		Email string `json:"email"`
+		Proof string `json:"proof"`
 	}
 
 	err := app.readJSON(w, r, &input)
 	if err != nil {
 		app.badRequestResponse(w, r, err)
+		return
 	}
 
-	user := &data.User{
-		Email: input.Email,
-		Name:  strings.Split(input.Email, "@")[0],
-	}
 	v := validator.New()
 	data.ValidateEmail(v, input.Email)
 	if !v.Valid() {
@@ -45,33 +66,84 @@ func (app *application) registerUserWithMagicLinkHandler(w http.ResponseWriter,
 		return
 	}
 
-	dbUser, err := app.models.Users.Get(0, user.Email)
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
-		if errors.Is(err, data.ErrRecordNotFound) {
-			err = app.models.Users.Insert(user)
-			if err != nil {
-				app.serverErrorResponse(w, r, err)
-			}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	client := clientKey(ip)
+	if ok, wait := app.ipThrottle.Allow(client, time.Now()); !ok {
+		app.cooldownResponse(w, r, wait)
+		return
+	}
+
+	// The proof is checked before the address is throttled so that, with a
+	// challenge configured, nobody can lock others out of their account
+	// without passing it.
+	err = app.verifier.Verify(r.Context(), input.Proof, input.Email, ip)
+	if err != nil {
+		if errors.Is(err, challenge.ErrFailed) {
+			app.challengeFailedResponse(w, r)
 		} else {
 			app.serverErrorResponse(w, r, err)
 		}
-	} else {
-		user = dbUser
+		return
 	}
 
-	magicLinkToken, err := tokens.CreateMagicLinkToken(user.Email)
+	suppressed, err := app.models.Suppressions.IsSuppressed(input.Email)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if suppressed {
+		app.emailSuppressedResponse(w, r)
+		return
+	}
+
+	// The cooldown is checked first so that rejected requests do not use up
+	// the hourly links of the address.
+	if ok, wait := app.cooldownThrottle.Allow(app.cooldownKey(input.Email, client), time.Now()); !ok {
+		app.cooldownResponse(w, r, wait)
+		return
+	}
+	if ok, wait := app.emailThrottle.Allow(strings.ToLower(input.Email), time.Now()); !ok {
+		app.cooldownResponse(w, r, wait)
+		return
+	}
+
+	// Known users get the email in their own language; the response is
+	// the same either way.
+	locale := app.templates.Locale(r.Header.Get("Accept-Language"))
+	user, err := app.models.Users.Get(0, input.Email)
+	switch {
+	case err == nil:
+		locale = app.templates.Locale(user.Language, r.Header.Get("Accept-Language"))
+	case !errors.Is(err, data.ErrRecordNotFound):
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	magicLinkToken, err := tokens.CreateMagicLinkToken(input.Email)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 	magicLink := URL + "/v1/auth/magic-link/" + magicLinkToken
 	emailData := struct {
-		MagicLinkToken string
+		MagicLink string
 	}{
-		MagicLinkToken: magicLink,
+		MagicLink: magicLink,
 	}
 
-	err = app.sendEmail("./templates/magic-email.tmpl", emailData, user.Email, "Magic Link - Web Dev Tools")
+	// Clients may send an Idempotency-Key so a retried request does not
+	// mail a second link.
+	idempotencyKey := ""
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		idempotencyKey = "magic-link:" + input.Email + ":" + key
+	}
+
+	err = app.queueEmail("magic-link", locale, emailData, input.Email, idempotencyKey, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -81,9 +153,37 @@ func (app *application) registerUserWithMagicLinkHandler(w http.ResponseWriter,
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
-	
 }
 
+// cooldownKey is what the wait between magic links to an address is keyed
+// by. Without a challenge anyone can request links for any address, so the
+// cooldown only applies per client; otherwise a stranger could keep its
+// owner from ever getting a link. The hourly cap per address applies
+// regardless.
+func (app *application) cooldownKey(email, client string) string {
+	key := strings.ToLower(email)
+	if _, ok := app.verifier.(challenge.None); ok {
+		key += " " + client
+	}
+	return key
+}
+
+// clientKey is what requests from ip are throttled by. IPv6 clients are
+// usually handed a whole /64, so they are keyed by that prefix.
+func clientKey(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	addr = addr.Unmap()
+	if addr.Is4() {
+		return addr.String()
+	}
+	return netip.PrefixFrom(addr.WithZone(""), 64).Masked().String()
+}
+
+// authenticateUserWithMagicLinkHandler signs in the owner of a magic link,
+// creating their account on first use.
 func (app *application) authenticateUserWithMagicLinkHandler(w http.ResponseWriter, r *http.Request) {
 	session := app.contextGetUser(r)
 
@@ -100,7 +200,7 @@ func (app *application) authenticateUserWithMagicLinkHandler(w http.ResponseWrit
 		return
 	}
 
-	user, err := app.models.Users.Get(0, email)
+	user, err := app.userForMagicLink(email, r.Header.Get("Accept-Language"))
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -118,6 +218,29 @@ func (app *application) authenticateUserWithMagicLinkHandler(w http.ResponseWrit
 	http.Redirect(w, r, app.config.ClientAddress, http.StatusFound)
 }
 
+// userForMagicLink returns the user with email, registering them if this is
+// their first sign in. A link clicked twice at once may race to register;
+// the loser picks up the winner's user.
+func (app *application) userForMagicLink(email, acceptLanguage string) (*data.User, error) {
+	user, err := app.models.Users.Get(0, email)
+	if !errors.Is(err, data.ErrRecordNotFound) {
+		return user, err
+	}
+
+	user = &data.User{
+		Email:    email,
+		Name:     strings.Split(email, "@")[0],
+		Language: app.templates.Locale(acceptLanguage),
+	}
+	err = app.models.Users.Insert(user)
+	if errors.Is(err, data.ErrDuplicateEmail) {
+		return app.models.Users.Get(0, email)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
 
 func (app *application) logoutHandler(w http.ResponseWriter, r *http.Request) {
 	session := app.contextGetUser(r)
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wdt/internal/challenge"
+	"github.com/wdt/internal/data"
+	"github.com/wdt/internal/random"
+	"github.com/wdt/internal/throttle"
+	"github.com/wdt/internal/tokens"
+)
+
+func requestMagicLink(t *testing.T, h http.Handler, email, proof, ip string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]string{"email": email, "proof": proof})
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/auth/magic-link", bytes.NewReader(body))
+	r.RemoteAddr = ip + ":1234"
+	r.Header.Set("Accept-Language", "de")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func clickMagicLink(t *testing.T, h http.Handler, email string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	token, err := tokens.CreateMagicLinkToken(email)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/auth/magic-link/"+token, nil)
+	r.Header.Set("Accept-Language", "de-AT,en;q=0.5")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func retryAfter(t *testing.T, w *httptest.ResponseRecorder) time.Duration {
+	t.Helper()
+
+	seconds, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	return time.Duration(seconds) * time.Second
+}
+
+func randomEmail() string {
+	return random.RandString(10) + "@gmail.com"
+}
+
+func TestMagicLink_RejectsMissingProof(t *testing.T) {
+	app := newTestApp(t)
+	app.verifier = challenge.Stub{Proof: "ok"}
+	h := app.routes()
+
+	w := requestMagicLink(t, h, randomEmail(), "", "10.0.0.1")
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	w = requestMagicLink(t, h, randomEmail(), "wrong", "10.0.0.1")
+	require.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestMagicLink_ThrottlesIP(t *testing.T) {
+	app := newTestApp(t)
+	app.verifier = challenge.Stub{Proof: "ok"}
+	app.ipThrottle = throttle.New(1, time.Hour, 0)
+	h := app.routes()
+
+	// Requests count against the IP even when their proof is wrong.
+	w := requestMagicLink(t, h, randomEmail(), "wrong", "10.0.0.2")
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	w = requestMagicLink(t, h, randomEmail(), "ok", "10.0.0.2")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.InDelta(t, time.Hour, retryAfter(t, w), float64(time.Second))
+}
+
+func TestMagicLink_ThrottlesIPv6Prefix(t *testing.T) {
+	app := newTestApp(t)
+	app.ipThrottle = throttle.New(1, time.Hour, 0)
+	h := app.routes()
+
+	w := requestMagicLink(t, h, randomEmail(), "", "[2001:db8:1:2::1]")
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	// Other addresses in the same /64 count against the same quota.
+	w = requestMagicLink(t, h, randomEmail(), "", "[2001:db8:1:2:ffff::2]")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	w = requestMagicLink(t, h, randomEmail(), "", "[2001:db8:1:3::1]")
+	require.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestClientKey(t *testing.T) {
+	require.Equal(t, "10.0.0.1", clientKey("10.0.0.1"))
+	require.Equal(t, "10.0.0.1", clientKey("::ffff:10.0.0.1"))
+	require.Equal(t, "2001:db8:1:2::/64", clientKey("2001:db8:1:2:aaaa:bbbb:cccc:dddd"))
+	require.Equal(t, "2001:db8:1:2::/64", clientKey("2001:db8:1:2::1%eth0"))
+}
+
+func TestMagicLink_EmailCooldown(t *testing.T) {
+	app := newTestApp(t)
+	h := app.routes()
+	email := randomEmail()
+
+	w := requestMagicLink(t, h, email, "", "10.0.0.3")
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	w = requestMagicLink(t, h, email, "", "10.0.0.3")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.InDelta(t, time.Minute, retryAfter(t, w), float64(time.Second))
+
+	// Without a challenge other clients can still ask for the address.
+	w = requestMagicLink(t, h, email, "", "10.0.0.4")
+	require.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestMagicLink_EmailHourlyCap(t *testing.T) {
+	app := newTestApp(t)
+	app.emailThrottle = throttle.New(2, time.Hour, 0)
+	h := app.routes()
+	email := randomEmail()
+
+	// Switching clients does not buy more links for the same address.
+	w := requestMagicLink(t, h, email, "", "10.0.1.1")
+	require.Equal(t, http.StatusCreated, w.Code)
+	w = requestMagicLink(t, h, email, "", "10.0.1.2")
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	w = requestMagicLink(t, h, email, "", "10.0.1.3")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.InDelta(t, time.Hour, retryAfter(t, w), float64(time.Second))
+}
+
+func TestMagicLink_EmailCooldownWithChallenge(t *testing.T) {
+	app := newTestApp(t)
+	app.verifier = challenge.Stub{Proof: "ok"}
+	h := app.routes()
+	email := randomEmail()
+
+	w := requestMagicLink(t, h, email, "ok", "10.0.0.5")
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	w = requestMagicLink(t, h, email, "ok", "10.0.0.6")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestMagicLink_CreatesUserOnFirstClick(t *testing.T) {
+	app := newTestApp(t)
+	h := app.routes()
+	email := randomEmail()
+
+	w := requestMagicLink(t, h, email, "", "10.0.0.7")
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	_, err := app.models.Users.Get(0, email)
+	require.ErrorIs(t, err, data.ErrRecordNotFound)
+
+	w = clickMagicLink(t, h, email)
+	require.Equal(t, http.StatusFound, w.Code)
+	require.Equal(t, app.config.ClientAddress, w.Header().Get("Location"))
+	require.NotEmpty(t, w.Result().Cookies())
+
+	user, err := app.models.Users.Get(0, email)
+	require.NoError(t, err)
+	require.Equal(t, "de", user.Language)
+
+	w = clickMagicLink(t, h, email)
+	require.Equal(t, http.StatusFound, w.Code)
+
+	again, err := app.models.Users.Get(0, email)
+	require.NoError(t, err)
+	require.Equal(t, user.ID, again.ID)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/wdt/internal/data"
+	validator "github.com/wdt/internal/validators"
+)
+
+func (app *application) bulkToolsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		IDs      []int64 `json:"ids"`
+		Action   string  `json:"action"`
+		Category string  `json:"category"`
+		DryRun   bool    `json:"dryRun"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	action := data.BulkToolAction{
+		IDs:      input.IDs,
+		Action:   input.Action,
+		Category: input.Category,
+		DryRun:   input.DryRun,
+	}
+
+	v := validator.New()
+	if data.ValidateBulkToolAction(v, action); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	results, err := app.models.Tools.Bulk(action, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrUnknownCategory):
+			v.AddError("category", "does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"results": results, "dryRun": action.DryRun}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/wdt/internal/data"
+	validator "github.com/wdt/internal/validators"
+)
+
+var catalogColumns = []string{"id", "name", "slug", "category", "description", "website", "published"}
+
+func (app *application) exportToolsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	format := app.readString(r.URL.Query(), "format", "json")
+
+	if v.Check(validator.PermittedValue(format, "csv", "json", "ndjson"), "format", "must be csv, json or ndjson"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	contentTypes := map[string]string{
+		"csv":    "text/csv",
+		"json":   "application/json",
+		"ndjson": "application/x-ndjson",
+	}
+	w.Header().Set("Content-Type", contentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="tools.%s"`, format))
+
+	// Nothing is written until the first tool arrives, so a failing query can
+	// still be reported as a normal error response.
+	started := false
+	var write func(tool *data.Tool) error
+
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		defer cw.Flush()
+
+		write = func(tool *data.Tool) error {
+			if !started {
+				if err := cw.Write(catalogColumns); err != nil {
+					return err
+				}
+			}
+			if tool == nil {
+				return nil
+			}
+			return cw.Write([]string{
+				strconv.FormatInt(tool.ID, 10),
+				tool.Name,
+				tool.Slug,
+				tool.Category,
+				tool.Description,
+				tool.Website,
+				strconv.FormatBool(tool.Published),
+			})
+		}
+	case "ndjson":
+		enc := json.NewEncoder(w)
+
+		write = func(tool *data.Tool) error {
+			return enc.Encode(tool.CatalogEntry())
+		}
+	case "json":
+		enc := json.NewEncoder(w)
+
+		write = func(tool *data.Tool) error {
+			separator := ","
+			if !started {
+				separator = "["
+			}
+			if _, err := io.WriteString(w, separator); err != nil {
+				return err
+			}
+			return enc.Encode(tool.CatalogEntry())
+		}
+	}
+
+	err := app.models.Tools.Export(r.Context(), func(tool *data.Tool) error {
+		err := write(tool)
+		started = true
+		return err
+	})
+	if err != nil {
+		if !started {
+			w.Header().Del("Content-Disposition")
+			app.serverErrorResponse(w, r, err)
+		} else {
+			app.logError(r, err)
+		}
+		return
+	}
+
+	switch {
+	case format == "json" && started:
+		_, err = io.WriteString(w, "]\n")
+	case format == "json":
+		_, err = io.WriteString(w, "[]\n")
+	case format == "csv" && !started:
+		// An empty catalog still gets its header row.
+		err = write(nil)
+	}
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+func (app *application) importToolsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	dryRun := app.readBool(qs, "dryRun", false, v)
+	format := app.readString(qs, "format", "")
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "text/csv":
+			format = "csv"
+		case "application/x-ndjson":
+			format = "ndjson"
+		default:
+			format = "json"
+		}
+	}
+	v.Check(validator.PermittedValue(format, "csv", "json", "ndjson"), "format", "must be csv, json or ndjson")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, 10<<20)
+
+	var entries []data.CatalogEntry
+	var err error
+
+	switch format {
+	case "csv":
+		entries, err = readCatalogCSV(body)
+	case "ndjson":
+		entries, err = readCatalogNDJSON(body)
+	case "json":
+		err = json.NewDecoder(body).Decode(&entries)
+	}
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if v.Check(len(entries) > 0, "body", "must contain at least one tool"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	if v.Check(len(entries) <= 5000, "body", "must not contain more than 5000 tools"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	results, err := app.models.Tools.Import(entries, dryRun, app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	summary := make(map[string]int)
+	for _, result := range results {
+		summary[result.Status]++
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"results": results, "summary": summary, "dryRun": dryRun}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readCatalogCSV reads a catalog with a header row naming its columns. Only
+// the name column is required; unknown columns are ignored and missing ones
+// leave the matching field of an existing tool untouched.
+func readCatalogCSV(body io.Reader) ([]data.CatalogEntry, error) {
+	cr := csv.NewReader(body)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("body must not be empty")
+		}
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("csv header must contain a name column")
+	}
+
+	var entries []data.CatalogEntry
+	for row := 1; ; row++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		optional := func(name string) *string {
+			if _, ok := columns[name]; !ok {
+				return nil
+			}
+			value := field(name)
+			return &value
+		}
+
+		entry := data.CatalogEntry{
+			Name:        field("name"),
+			Slug:        field("slug"),
+			Category:    optional("category"),
+			Description: optional("description"),
+			Website:     optional("website"),
+		}
+		if published := field("published"); published != "" {
+			b, err := strconv.ParseBool(published)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: published must be a boolean value", row)
+			}
+			entry.Published = &b
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func readCatalogNDJSON(body io.Reader) ([]data.CatalogEntry, error) {
+	dec := json.NewDecoder(body)
+
+	var entries []data.CatalogEntry
+	for row := 1; ; row++ {
+		var entry data.CatalogEntry
+		err := dec.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -12,7 +13,10 @@ import (
 
 func (app *application) createCategoryHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Name string `json:"name"`
+		Name        string `json:"name"`
+		ParentID    int64  `json:"parentId"`
+		Description string `json:"description"`
+		Icon        string `json:"icon"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -22,7 +26,10 @@ func (app *application) createCategoryHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	category := &data.Category{
-		Name: input.Name,
+		Name:        input.Name,
+		ParentID:    input.ParentID,
+		Description: input.Description,
+		Icon:        input.Icon,
 	}
 
 	v := validator.New()
@@ -35,7 +42,16 @@ func (app *application) createCategoryHandler(w http.ResponseWriter, r *http.Req
 
 	err = app.models.Categories.Insert(category)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("parentId", "parent category does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrDuplicateCategoryName):
+			v.AddError("name", "a category with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
@@ -46,6 +62,188 @@ func (app *application) createCategoryHandler(w http.ResponseWriter, r *http.Req
 	}
 }
 
+func (app *application) updateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	params := chi.URLParam(r, "id")
+
+	id, err := strconv.ParseInt(params, 10, 64)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Name        *string `json:"name"`
+		ParentID    *int64  `json:"parentId"`
+		Description *string `json:"description"`
+		Icon        *string `json:"icon"`
+		Position    *int    `json:"position"`
+		Published   *bool   `json:"published"`
+		Version     *int64  `json:"version"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	category, err := app.models.Categories.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !app.checkVersion(w, r, category.ID, category.Version, input.Version) {
+		return
+	}
+
+	if input.Name != nil {
+		category.Name = *input.Name
+	}
+	if input.ParentID != nil {
+		category.ParentID = *input.ParentID
+	}
+	if input.Description != nil {
+		category.Description = *input.Description
+	}
+	if input.Icon != nil {
+		category.Icon = *input.Icon
+	}
+	if input.Position != nil {
+		category.Position = *input.Position
+	}
+	if input.Published != nil {
+		category.Published = *input.Published
+	}
+
+	v := validator.New()
+	if data.ValidateCategories(v, category); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Categories.Update(category, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateCategoryName):
+			v.AddError("name", "a category with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrCategoryCycle):
+			v.AddError("parentId", "category cannot be moved below itself")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("parentId", "parent category does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", versionETag(category.ID, category.Version))
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"category": category}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) mergeCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	params := chi.URLParam(r, "id")
+
+	id, err := strconv.ParseInt(params, 10, 64)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		TargetID int64 `json:"targetId"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.TargetID > 0, "targetId", "must be provided")
+	v.Check(input.TargetID != id, "targetId", "must be a different category")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	moved, err := app.models.Categories.Merge(id, input.TargetID, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "categories successfully merged", "movedTools": moved}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) reorderCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		IDs []int64 `json:"ids"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(len(input.IDs) > 0, "ids", "must contain at least one id")
+	v.Check(validator.Unique(input.IDs), "ids", "must not contain duplicate values")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Categories.Reorder(input.IDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	categories, err := app.models.Categories.GetAllPublished()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"categories": categories}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteCategoryHandler refuses to delete a category that still has tools
+// unless the reassignTo query parameter names a category to move them to.
 func (app *application) deleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
 	params := chi.URLParam(r, "id")
 
@@ -55,11 +253,25 @@ func (app *application) deleteCategoryHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	err = app.models.Categories.Delete(id)
+	v := validator.New()
+	reassignTo := int64(app.readInt(r.URL.Query(), "reassignTo", 0, v))
+	v.Check(reassignTo != id, "reassignTo", "must be a different category")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if reassignTo > 0 {
+		_, err = app.models.Categories.Merge(id, reassignTo, app.contextGetUser(r).ID)
+	} else {
+		err = app.models.Categories.Delete(id)
+	}
 	if err != nil {
 		switch err {
 		case data.ErrRecordNotFound:
 			app.notFoundResponse(w, r)
+		case data.ErrCategoryInUse:
+			app.categoryInUseResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
@@ -92,13 +304,17 @@ func (app *application) toggleCategoryPublishedHandler(w http.ResponseWriter, r
 		return
 	}
 
+	if !app.checkVersion(w, r, category.ID, category.Version, queryVersion(r)) {
+		return
+	}
+
 	category.Published = !category.Published
 
-	err = app.models.Categories.Update(category)
+	err = app.models.Categories.Update(category, app.contextGetUser(r).ID)
 	if err != nil {
 		switch err {
 		case data.ErrEditConflict:
-			app.notFoundResponse(w, r)
+			app.editConflictResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
@@ -118,7 +334,20 @@ func (app *application) getCategoriesHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"categories": categories}, nil)
+	err = app.writeCacheableJSON(w, r, envelope{"categories": categories}, "")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getCategoryTreeHandler(w http.ResponseWriter, r *http.Request) {
+	categories, err := app.models.Categories.GetAllPublished()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeCacheableJSON(w, r, envelope{"categories": data.BuildCategoryTree(categories)}, "")
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -135,7 +364,7 @@ func (app *application) getAdminCategoriesHandler(w http.ResponseWriter, r *http
 	qs := r.URL.Query()
 
 	meta.Filters.Sort = app.readString(qs, "sort", "-id")
-	meta.Filters.SortSafelist = []string{"name", "id", "-name", "-id", "published", "-published"}
+	meta.Filters.SortSafelist = []string{"name", "id", "-name", "-id", "published", "-published", "position", "-position"}
 	meta.Filters.Page = app.readInt(qs, "page", 1, v)
 	meta.Filters.PageSize = app.readInt(qs, "pageSize", 20, v)
 
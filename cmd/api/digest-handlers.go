@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/wdt/internal/data"
+	"github.com/wdt/internal/tokens"
+)
+
+// confirmDigestUnsubscribeHandler handles a click on the unsubscribe link.
+// Link scanners and prefetchers follow links too, so it changes nothing and
+// sends the user on to the client, which asks for confirmation and then
+// makes the POST.
+func (app *application) confirmDigestUnsubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+
+	_, err := tokens.ValidateUnsubscribeToken("digest", token)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, app.config.ClientAddress+"/unsubscribe/digest?token="+url.QueryEscape(token), http.StatusFound)
+}
+
+// unsubscribeDigestHandler turns off the weekly digest for the user the
+// token was made for. It is called by the client's confirmation page and by
+// mail clients for the List-Unsubscribe-Post header (RFC 8058).
+func (app *application) unsubscribeDigestHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := tokens.ValidateUnsubscribeToken("digest", r.URL.Query().Get("token"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Digests.Unsubscribe(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "unsubscribed from the weekly digest"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wdt/internal/data"
+	"github.com/wdt/internal/tokens"
+)
+
+func TestDigestUnsubscribe_GetOnlyRedirects(t *testing.T) {
+	app := newTestApp(t)
+	h := app.routes()
+
+	user := &data.User{Email: randomEmail(), Language: "en"}
+	err := app.models.Users.Insert(user)
+	require.NoError(t, err)
+	user, err = app.models.Users.Get(user.ID, "")
+	require.NoError(t, err)
+	user.Digest = true
+	err = app.models.Users.UpdatePreferences(user)
+	require.NoError(t, err)
+
+	token, err := tokens.CreateUnsubscribeToken("digest", user.ID)
+	require.NoError(t, err)
+	target := "/v1/digest/unsubscribe?token=" + url.QueryEscape(token)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	require.Equal(t, http.StatusFound, w.Code)
+	require.Equal(t, app.config.ClientAddress+"/unsubscribe/digest?token="+url.QueryEscape(token), w.Header().Get("Location"))
+
+	dbUser, err := app.models.Users.Get(user.ID, "")
+	require.NoError(t, err)
+	require.True(t, dbUser.Digest)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, target, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	dbUser, err = app.models.Users.Get(user.ID, "")
+	require.NoError(t, err)
+	require.False(t, dbUser.Digest)
+}
+
+func TestDigestUnsubscribe_RejectsInvalidToken(t *testing.T) {
+	app := newTestApp(t)
+	h := app.routes()
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, "/v1/digest/unsubscribe?token=forged", nil))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	}
+}
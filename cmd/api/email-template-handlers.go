@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	validator "github.com/wdt/internal/validators"
+	"github.com/wdt/templates"
+)
+
+// getEmailTemplatesHandler lists the email templates with the locales each
+// one is available in.
+func (app *application) getEmailTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	err := app.writeJSON(w, http.StatusOK, envelope{"templates": app.templates.Names(), "locales": app.templates.Locales()}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// previewEmailTemplateHandler renders an email template with its sample
+// data. With format=html the HTML part is returned as a page so it can be
+// viewed in the browser.
+func (app *application) previewEmailTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	locale := app.readString(qs, "locale", templates.DefaultLocale)
+	format := app.readString(qs, "format", "json")
+
+	v.Check(validator.PermittedValue(locale, app.templates.Locales()...), "locale", "is not supported")
+	v.Check(validator.PermittedValue(format, "json", "html", "text"), "format", "must be json, html or text")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+	sample, err := app.templates.Sample(name)
+	if err != nil {
+		switch {
+		case errors.Is(err, templates.ErrUnknownTemplate):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	email, err := app.templates.Render(name, locale, sample)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	switch format {
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(email.HTML))
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(email.Text))
+	default:
+		err = app.writeJSON(w, http.StatusOK, envelope{"subject": email.Subject, "html": email.HTML, "text": email.Text, "data": sample}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
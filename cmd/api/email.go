@@ -1,47 +1,34 @@
 package main
 
 import (
-	"bytes"
-	"html/template"
-
-	"github.com/resendlabs/resend-go"
+	"github.com/wdt/internal/data"
 )
 
-func (app *application) sendEmail(tmplPath string, data interface{}, recipient, subject string) error {
-	tmpl, err := template.ParseFiles(tmplPath)
-	if err != nil {
-		return err
-	}
-
-	var emailContent bytes.Buffer
-	err = tmpl.Execute(&emailContent, data)
+// queueEmail renders the named email template in locale and adds it to the
+// outbox, from where the outbox workers send it. A non-empty idempotency
+// key makes queueing the same email again a no-op. Headers are added to the
+// email as they are.
+func (app *application) queueEmail(name, locale string, tmplData interface{}, recipient, idempotencyKey string, headers data.EmailHeaders) error {
+	rendered, err := app.templates.Render(name, locale, tmplData)
 	if err != nil {
 		return err
 	}
 
-	mail := app.mailer.MailClient()
-
-	emailResponseChannel := make(chan error)
-	params := &resend.SendEmailRequest{
-		From:    "Web Dev Tools <info@web-dev-tools.xyz>",
-		To:      []string{recipient},
-		Subject: subject,
-		Html:    emailContent.String(),
+	email := &data.OutboxEmail{
+		IdempotencyKey: idempotencyKey,
+		From:           app.config.MailFrom,
+		To:             recipient,
+		Subject:        rendered.Subject,
+		HTML:           rendered.HTML,
+		Text:           rendered.Text,
+		Headers:        headers,
 	}
 
-	go func() {
-		_, err := mail.Emails.Send(params)
-		if err != nil {
-			emailResponseChannel <- err
-		}
-		close(emailResponseChannel)
-	}()
-	err, ok := <-emailResponseChannel
-	if !ok {
-		if err != nil {
-			return err
-		}
+	err = app.models.Outbox.Enqueue(email)
+	if err != nil {
+		return err
 	}
 
+	app.wakeOutbox()
 	return nil
-}
\ No newline at end of file
+}
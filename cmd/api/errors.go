@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 func (app *application) logError(r *http.Request, err error) {
@@ -54,6 +56,26 @@ func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.
 	app.errorResponse(w, r, http.StatusUnprocessableEntity, errors)
 }
 
+func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Request) {
+	message := "unable to update the record due to an edit conflict, please try again"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (app *application) preconditionFailedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the record has been modified since you last fetched it"
+	app.errorResponse(w, r, http.StatusPreconditionFailed, message)
+}
+
+func (app *application) preconditionRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "updates must include an If-Match header or a version field"
+	app.errorResponse(w, r, http.StatusPreconditionRequired, message)
+}
+
+func (app *application) categoryInUseResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the category still has tools, pass reassignTo with the id of a category to move them to"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
 func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
 	message := "your user account doesn't have the necessary permissions to access this resource"
 	app.errorResponse(w, r, http.StatusForbidden, message)
@@ -67,4 +89,27 @@ func (app *application) alreadyHaveSessionResponse(w http.ResponseWriter, r *htt
 func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
     message := "rate limit exceeded"
     app.errorResponse(w, r, http.StatusTooManyRequests, message)
-}
\ No newline at end of file
+}
+
+func (app *application) quotaExceededResponse(w http.ResponseWriter, r *http.Request, resetsAt time.Time) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(resetsAt).Seconds())+1))
+	message := "daily upload quota exceeded, see /v1/users/quota"
+	app.errorResponse(w, r, http.StatusTooManyRequests, message)
+}
+
+func (app *application) cooldownResponse(w http.ResponseWriter, r *http.Request, wait time.Duration) {
+	seconds := int(wait.Seconds()) + 1
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	message := fmt.Sprintf("too many magic links requested, please try again in %d seconds", seconds)
+	app.errorResponse(w, r, http.StatusTooManyRequests, message)
+}
+
+func (app *application) challengeFailedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the challenge proof is missing or invalid, see /v1/auth/challenge"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+func (app *application) emailSuppressedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "email to this address bounced permanently, so no more email is sent to it; please use a different address"
+	app.errorResponse(w, r, http.StatusUnprocessableEntity, message)
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -38,6 +40,102 @@ func (app *application) writeJSON(w http.ResponseWriter, status int, data interf
 	return nil
 }
 
+// writeCacheableJSON writes data like writeJSON with an ETag header and
+// answers 304 Not Modified when If-None-Match already names that ETag. When
+// etag is empty it is derived from the response body.
+func (app *application) writeCacheableJSON(w http.ResponseWriter, r *http.Request, data interface{}, etag string) error {
+	js, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	js = append(js, '\n')
+
+	if etag == "" {
+		sum := sha256.Sum256(js)
+		etag = `W/"` + hex.EncodeToString(sum[:16]) + `"`
+	}
+	w.Header().Set("ETag", etag)
+
+	if notModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(js)
+	return err
+}
+
+// versionETag builds the entity tag of a versioned record. Every update bumps
+// the version, so the tag changes whenever the record does.
+func versionETag(id, version int64) string {
+	return fmt.Sprintf(`"%d-%d"`, id, version)
+}
+
+// notModified reports whether the client already has the response with etag,
+// in which case writeCacheableJSON answers 304 Not Modified.
+func notModified(r *http.Request, etag string) bool {
+	match := r.Header.Get("If-None-Match")
+	return match != "" && etagMatches(match, etag, false)
+}
+
+// etagMatches reports whether a comma separated If-Match or If-None-Match
+// header names etag. If-None-Match uses weak comparison; If-Match must use
+// strong comparison, under which weak tags never match (RFC 9110 8.8.3.2).
+func etagMatches(header, etag string, strong bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strong {
+			if !strings.HasPrefix(candidate, "W/") && !strings.HasPrefix(etag, "W/") && candidate == etag {
+				return true
+			}
+			continue
+		}
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkVersion compares the version the client last saw, sent either as an
+// If-Match header or as a version field, with the stored one. It writes the
+// error response and returns false when the update must not go ahead.
+func (app *application) checkVersion(w http.ResponseWriter, r *http.Request, id, current int64, version *int64) bool {
+	if match := r.Header.Get("If-Match"); match != "" {
+		if !etagMatches(match, versionETag(id, current), true) {
+			app.preconditionFailedResponse(w, r)
+			return false
+		}
+		return true
+	}
+
+	if version == nil {
+		app.preconditionRequiredResponse(w, r)
+		return false
+	}
+	if *version != current {
+		app.editConflictResponse(w, r)
+		return false
+	}
+	return true
+}
+
+// queryVersion reads the version query parameter, which stands in for the
+// version field on requests without a body. It returns nil when the
+// parameter is missing or not a number.
+func queryVersion(r *http.Request) *int64 {
+	version, err := strconv.ParseInt(r.URL.Query().Get("version"), 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &version
+}
+
 func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst interface{}) error {
 	maxBytes := 1_048_576 // 1MB
 	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
@@ -119,6 +217,32 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	return i
 }
 
+func (app *application) readBool(qs url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "Must be a boolean value")
+		return defaultValue
+	}
+	return b
+}
+
+func (app *application) readDate(qs url.Values, key string, defaultValue time.Time, v *validator.Validator) time.Time {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		v.AddError(key, "Must be a date in YYYY-MM-DD format")
+		return defaultValue
+	}
+	return d
+}
+
 func (app *application) githubConfig() *oauth2.Config {
 	githubOauthConfig := &oauth2.Config{
 		ClientID:     app.config.GithubClientID,
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEtagMatches(t *testing.T) {
+	require.True(t, etagMatches(`"1-2"`, `"1-2"`, true))
+	require.True(t, etagMatches(`"1-1", "1-2"`, `"1-2"`, true))
+	require.True(t, etagMatches(`*`, `"1-2"`, true))
+	require.False(t, etagMatches(`W/"1-2"`, `"1-2"`, true))
+	require.False(t, etagMatches(`"1-2"`, `W/"1-2"`, true))
+	require.False(t, etagMatches(`"1-1"`, `"1-2"`, true))
+
+	require.True(t, etagMatches(`W/"1-2"`, `"1-2"`, false))
+	require.True(t, etagMatches(`"abc"`, `W/"abc"`, false))
+	require.False(t, etagMatches(`"abc"`, `W/"abd"`, false))
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/wdt/internal/data"
+	"github.com/wdt/internal/tokens"
+)
+
+// startJobs launches the background jobs. They run until ctx is cancelled
+// during shutdown, and serve waits for them through app.wg.
+func (app *application) startJobs(ctx context.Context) {
+	app.runPeriodically(ctx, "purge trash", time.Hour, app.purgeTrash)
+	app.runPeriodically(ctx, "publish schedule", time.Minute, app.applyPublishSchedule)
+	app.runAtStartup(ctx, "refresh recommendations", 30*time.Minute, app.refreshRecommendations)
+	app.runPeriodically(ctx, "flush analytics", 10*time.Second, app.flushAnalytics)
+	app.runPeriodically(ctx, "check websites", 10*time.Minute, app.checkWebsites)
+	app.runPeriodically(ctx, "sweep uploads", time.Hour, app.sweepUploads)
+	if app.config.DigestEnabled {
+		app.runPeriodically(ctx, "send digests", time.Hour, app.sendDigests)
+	}
+	app.runPeriodically(ctx, "sweep throttles", 10*time.Minute, app.sweepThrottles)
+	app.startOutbox(ctx)
+}
+
+// runPeriodically calls fn once every interval on a goroutine tracked by
+// app.wg until ctx is cancelled. Errors are logged and the job keeps running.
+func (app *application) runPeriodically(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context) error) {
+	app.runJob(ctx, name, interval, false, fn)
+}
+
+// runAtStartup is like runPeriodically but also calls fn right away, for
+// jobs whose results should not wait for the first interval after a deploy.
+func (app *application) runAtStartup(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context) error) {
+	app.runJob(ctx, name, interval, true, fn)
+}
+
+func (app *application) runJob(ctx context.Context, name string, interval time.Duration, now bool, fn func(ctx context.Context) error) {
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.Error().Interface("panic", err).Str("job", name).Msg("background job crashed")
+			}
+		}()
+
+		run := func() {
+			err := fn(ctx)
+			if err != nil {
+				app.logger.Error().Err(err).Str("job", name).Msg("background job failed")
+			}
+		}
+
+		if now {
+			run()
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				run()
+			}
+		}
+	}()
+}
+
+// purgeTrash deletes trashed tools and categories older than the retention
+// period. A retention of 0 days keeps the trash forever.
+func (app *application) purgeTrash(ctx context.Context) error {
+	if app.config.TrashRetentionDays == 0 {
+		return nil
+	}
+	retention := time.Duration(app.config.TrashRetentionDays) * 24 * time.Hour
+
+	tools, err := app.models.Tools.PurgeDeleted(retention)
+	if err != nil {
+		return err
+	}
+
+	categories, err := app.models.Categories.PurgeDeleted(retention)
+	if err != nil {
+		return err
+	}
+
+	if tools > 0 || categories > 0 {
+		app.logger.Info().Int64("tools", tools).Int64("categories", categories).Msg("purged trash")
+	}
+	return nil
+}
+
+func (app *application) applyPublishSchedule(ctx context.Context) error {
+	tools, err := app.models.Tools.ApplySchedule()
+	if err != nil {
+		return err
+	}
+
+	categories, err := app.models.Categories.ApplySchedule()
+	if err != nil {
+		return err
+	}
+
+	if tools > 0 || categories > 0 {
+		app.logger.Info().Int("tools", tools).Int("categories", categories).Msg("applied publish schedule")
+	}
+	return nil
+}
+
+// refreshRecommendations rebuilds the per-user recommendations read by
+// GET /v1/users/recommendations.
+func (app *application) refreshRecommendations(ctx context.Context) error {
+	rows, err := app.models.Recommendations.Refresh(50, 10)
+	if err != nil {
+		return err
+	}
+
+	app.logger.Info().Int64("recommendations", rows).Msg("refreshed recommendations")
+	return nil
+}
+
+// flushAnalytics writes the buffered view and click counts. serve also calls
+// it once more after shutdown so nothing recorded is lost.
+func (app *application) flushAnalytics(ctx context.Context) error {
+	counts := app.analytics.Drain()
+
+	err := app.models.Analytics.Flush(counts)
+	if err != nil {
+		app.analytics.Restore(counts)
+		return err
+	}
+	return nil
+}
+
+// checkWebsites checks the websites that are due and records the results.
+// Each run handles a bounded batch so a large catalog is spread over several
+// runs.
+func (app *application) checkWebsites(ctx context.Context) error {
+	interval := time.Duration(app.config.LinkCheckIntervalHours) * time.Hour
+	flagAfter := time.Duration(app.config.LinkCheckFlagDays) * 24 * time.Hour
+
+	targets, err := app.models.WebsiteChecks.GetDue(interval, 100)
+	if err != nil {
+		return err
+	}
+
+	urls := make([]string, len(targets))
+	for i, target := range targets {
+		urls[i] = target.Website
+	}
+
+	results := app.links.CheckAll(ctx, urls)
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	flagged := 0
+	for i, result := range results {
+		health := &data.WebsiteHealth{
+			OK:          result.OK(),
+			StatusCode:  result.StatusCode,
+			LatencyMs:   result.Latency.Milliseconds(),
+			RedirectURL: result.RedirectURL,
+		}
+		if result.Err != nil {
+			health.Error = result.Err.Error()
+		}
+
+		err := app.models.WebsiteChecks.Record(targets[i].ToolID, health, flagAfter)
+		if err != nil {
+			return err
+		}
+		if health.Flagged {
+			flagged++
+		}
+	}
+
+	if len(targets) > 0 {
+		app.logger.Info().Int("checked", len(targets)).Int("flagged", flagged).Msg("checked websites")
+	}
+	return nil
+}
+
+// sweepUploads deletes uploads that were abandoned before completion or that
+// no tool has referenced for longer than the grace period, together with
+// their objects in storage.
+func (app *application) sweepUploads(ctx context.Context) error {
+	grace := time.Duration(app.config.UploadGraceHours) * time.Hour
+
+	uploads, err := app.models.Uploads.GetOrphaned(grace, 100)
+	if err != nil {
+		return err
+	}
+
+	deleted := 0
+	for _, upload := range uploads {
+		if ctx.Err() != nil {
+			break
+		}
+
+		// The record goes first so a tool can never end up pointing at an
+		// upload whose objects are already gone.
+		ok, err := app.models.Uploads.DeleteOrphaned(upload.ID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		for _, key := range uploadKeys(upload) {
+			err = app.storage.Delete(ctx, key)
+			if err != nil {
+				app.logger.Error().Err(err).Str("upload", upload.ID).Str("key", key).Msg("failed to delete upload object")
+			}
+		}
+		deleted++
+	}
+
+	if deleted > 0 {
+		app.logger.Info().Int("uploads", deleted).Msg("swept orphaned uploads")
+	}
+	return nil
+}
+
+// digestTool is a tool as listed in the weekly digest email.
+type digestTool struct {
+	Name        string
+	Category    string
+	Description string
+	URL         string
+}
+
+// sendDigests queues the weekly digest for every opted-in user whose last
+// one is a week old. Users without new tools in their categories get no
+// email, but their period still moves on.
+func (app *application) sendDigests(ctx context.Context) error {
+	recipients, err := app.models.Digests.GetDue(7*24*time.Hour, 100)
+	if err != nil {
+		return err
+	}
+
+	queued := 0
+	for _, recipient := range recipients {
+		if ctx.Err() != nil {
+			break
+		}
+
+		until := time.Now()
+		tools, err := app.models.Digests.NewTools(recipient.UserID, recipient.Since, until, 20)
+		if err != nil {
+			return err
+		}
+
+		if len(tools) > 0 {
+			err = app.queueDigest(recipient, tools)
+			if err != nil {
+				return err
+			}
+			queued++
+		}
+
+		err = app.models.Digests.MarkSent(recipient, until)
+		if err != nil && !errors.Is(err, data.ErrEditConflict) {
+			return err
+		}
+	}
+
+	if queued > 0 {
+		app.logger.Info().Int("digests", queued).Msg("queued weekly digests")
+	}
+	return nil
+}
+
+func (app *application) queueDigest(recipient *data.DigestRecipient, tools []*data.DigestTool) error {
+	token, err := tokens.CreateUnsubscribeToken("digest", recipient.UserID)
+	if err != nil {
+		return err
+	}
+	unsubscribeURL := URL + "/v1/digest/unsubscribe?token=" + url.QueryEscape(token)
+
+	emailData := struct {
+		Name           string
+		Tools          []digestTool
+		UnsubscribeURL string
+	}{
+		Name:           recipient.Name,
+		UnsubscribeURL: unsubscribeURL,
+	}
+	for _, tool := range tools {
+		emailData.Tools = append(emailData.Tools, digestTool{
+			Name:        tool.Name,
+			Category:    tool.Category,
+			Description: tool.Description,
+			URL:         fmt.Sprintf("%s/v1/tools/%d/visit", URL, tool.ID),
+		})
+	}
+
+	// Mail clients offer their own unsubscribe button for these headers
+	// and use the POST variant without opening the link.
+	headers := data.EmailHeaders{
+		"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+
+	// The key keeps a rerun after a crash from mailing the same period
+	// twice.
+	idempotencyKey := fmt.Sprintf("digest:%d:%d", recipient.UserID, recipient.Since.Unix())
+
+	return app.queueEmail("weekly-digest", app.templates.Locale(recipient.Language), emailData, recipient.Email, idempotencyKey, headers)
+}
+
+// sweepThrottles forgets addresses and IPs that have not requested a magic
+// link recently.
+func (app *application) sweepThrottles(ctx context.Context) error {
+	now := time.Now()
+	app.emailThrottle.Sweep(now)
+	app.cooldownThrottle.Sweep(now)
+	app.ipThrottle.Sweep(now)
+	return nil
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"fmt"
+	"net/http"
 
 	"os"
 	"sync"
@@ -11,9 +14,15 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/wdt/config"
-	"github.com/wdt/internal/aws"
+	"github.com/wdt/internal/challenge"
 	"github.com/wdt/internal/data"
+	"github.com/wdt/internal/linkcheck"
 	"github.com/wdt/internal/mailer"
+	"github.com/wdt/internal/scanner"
+	"github.com/wdt/internal/storage"
+	"github.com/wdt/internal/throttle"
+	"github.com/wdt/internal/tokens"
+	"github.com/wdt/templates"
 
 	_ "github.com/lib/pq"
 )
@@ -21,12 +30,25 @@ import (
 var URL = "http://localhost:8080"
 
 type application struct {
-	logger *zerolog.Logger
-	wg     sync.WaitGroup
-	config config.AppConfig
-	models data.Models
-	mailer mailer.Mailer
-	aws    aws.AWS
+	logger     *zerolog.Logger
+	wg         sync.WaitGroup
+	config     config.AppConfig
+	models     data.Models
+	mailer     mailer.Sender
+	outboxWake chan struct{}
+	storage    storage.Storage
+	quotas     map[string]data.UploadQuota
+	scanner    scanner.Scanner
+	templates  *templates.Templates
+	analytics  *data.AnalyticsBuffer
+	links      *linkcheck.Checker
+
+	// Magic links are capped per address and per client IP, spaced out by
+	// a cooldown and may require a proof from verifier.
+	emailThrottle    *throttle.Throttle
+	cooldownThrottle *throttle.Throttle
+	ipThrottle       *throttle.Throttle
+	verifier         challenge.Verifier
 }
 
 func main() {
@@ -44,12 +66,61 @@ func main() {
 	defer db.Close()
 	log.Logger.Info().Msg("Connected to database")
 
+	// Unsubscribe links in digests that were already sent must keep working
+	// after a restart and on every instance, which random keys cannot do.
+	switch {
+	case cfg.TokenSecret != "":
+		tokens.UseSecret(cfg.TokenSecret)
+	case cfg.DigestEnabled:
+		log.Fatal().Msg("TOKEN_SECRET is required to send digests; set it or DIGEST_ENABLED=false")
+	default:
+		log.Warn().Msg("TOKEN_SECRET is not set; magic links stop working when the server restarts")
+	}
+
+	store, err := openStorage(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to set up storage")
+	}
+
+	sender, err := newMailer(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to set up mailer")
+	}
+
+	emailTemplates, err := templates.Parse()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse email templates")
+	}
+
+	quotas, err := data.ParseRoleQuotas(cfg.UploadRoleQuotas)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse upload quotas")
+	}
+
+	verifier, err := newVerifier(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to set up challenge")
+	}
+
+	models := data.NewModels(db)
+
 	app := application{
-		logger: &log.Logger,
-		config: cfg,
-		models: data.NewModels(db),
-		mailer: mailer.NewMailer(cfg.ResendApiKey),
-		aws:    aws.NewAws(cfg.AwsAccessKey, cfg.AwsSecretKey),
+		logger:     &log.Logger,
+		config:     cfg,
+		models:     models,
+		mailer:     mailer.Suppressing{Sender: sender, List: models.Suppressions},
+		outboxWake: make(chan struct{}, 1),
+		storage:    store,
+		quotas:     quotas,
+		scanner:    newScanner(cfg),
+		templates:  emailTemplates,
+		analytics:  data.NewAnalyticsBuffer(),
+		links:      linkcheck.New(cfg.LinkCheckConcurrency),
+
+		emailThrottle:    throttle.New(cfg.MagicLinkEmailHourly, time.Hour, 0),
+		cooldownThrottle: throttle.New(0, 0, time.Duration(cfg.MagicLinkEmailCooldown)*time.Second),
+		ipThrottle:       throttle.New(cfg.MagicLinkIPHourly, time.Hour, 0),
+		verifier:         verifier,
 	}
 
 	err = app.serve()
@@ -59,6 +130,86 @@ func main() {
 	}
 }
 
+// openStorage returns the storage backend selected by STORAGE_BACKEND.
+func openStorage(cfg config.AppConfig) (storage.Storage, error) {
+	switch cfg.StorageBackend {
+	case "local":
+		publicURL := cfg.StoragePublicURL
+		if publicURL == "" {
+			publicURL = URL + "/v1/files"
+		}
+		return storage.NewLocal(cfg.StorageLocalDir, publicURL, cfg.StorageSigningKey)
+	case "s3":
+		return storage.NewS3(storage.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Bucket:    cfg.S3Bucket,
+			Region:    cfg.S3Region,
+			AccessKey: cfg.AwsAccessKey,
+			SecretKey: cfg.AwsSecretKey,
+			UseSSL:    cfg.S3UseSSL,
+			PathStyle: cfg.S3PathStyle,
+			PublicURL: cfg.StoragePublicURL,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.StorageBackend)
+	}
+}
+
+// newMailer returns the email backend selected by MAIL_BACKEND.
+func newMailer(cfg config.AppConfig) (mailer.Sender, error) {
+	switch cfg.MailBackend {
+	case "resend":
+		return mailer.NewResend(cfg.ResendApiKey), nil
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required for the smtp mail backend")
+		}
+		return mailer.NewSMTP(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPStartTLS), nil
+	case "file":
+		return mailer.NewFileDrop(cfg.MailDropDir)
+	default:
+		return nil, fmt.Errorf("unknown mail backend %q", cfg.MailBackend)
+	}
+}
+
+// newVerifier returns the challenge selected by CHALLENGE that magic link
+// requests have to pass.
+func newVerifier(cfg config.AppConfig) (challenge.Verifier, error) {
+	switch cfg.Challenge {
+	case "none":
+		return challenge.None{}, nil
+	case "pow":
+		// Challenges are signed with a key derived from TOKEN_SECRET so all
+		// instances, and the next one after a restart, accept them.
+		if cfg.TokenSecret == "" {
+			return nil, fmt.Errorf("TOKEN_SECRET is required for the pow challenge")
+		}
+		key := sha256.Sum256([]byte("challenge:" + cfg.TokenSecret))
+		return challenge.NewProofOfWork(key[:], cfg.ChallengeDifficulty, 10*time.Minute), nil
+	case "captcha":
+		if cfg.CaptchaSecret == "" {
+			return nil, fmt.Errorf("CAPTCHA_SECRET is required for the captcha challenge")
+		}
+		return &challenge.Captcha{
+			URL:     cfg.CaptchaVerifyURL,
+			Secret:  cfg.CaptchaSecret,
+			SiteKey: cfg.CaptchaSiteKey,
+			Client:  &http.Client{Timeout: 5 * time.Second},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown challenge %q", cfg.Challenge)
+	}
+}
+
+// newScanner returns the malware scanner selected by SCANNER. Without one
+// every upload is accepted.
+func newScanner(cfg config.AppConfig) scanner.Scanner {
+	if cfg.Scanner == "clamd" {
+		return scanner.NewClamd(cfg.ClamdAddress)
+	}
+	return scanner.Noop{}
+}
+
 func openDB(url string, maxOpenCon, maxIdleCon int, maxIdleTime time.Duration) (*sql.DB, error) {
 	db, err := sql.Open("postgres", url)
 	if err != nil {
@@ -19,10 +19,11 @@ func (app *application) enableCORS(next http.Handler) http.Handler {
 		if origin != "" {
 			w.Header().Set("Access-Control-Allow-Origin", app.config.ClientAddress)
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			w.Header().Set("Access-Control-Expose-Headers", "ETag")
 
 			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
 				w.Header().Set("Access-Control-Allow-Methods", "PATCH, DELETE, GET, POST")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match, If-None-Match, Idempotency-Key")
 				w.WriteHeader(http.StatusOK)
 				return
 			}
@@ -97,7 +98,7 @@ func (app *application) adminPermission(next http.HandlerFunc) http.HandlerFunc
 			return
 		}
 
-		if user.Role != "admin" {
+		if !user.IsAdmin() {
 			app.notPermittedResponse(w, r)
 			return
 		}
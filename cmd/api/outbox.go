@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wdt/internal/data"
+	"github.com/wdt/internal/mailer"
+)
+
+const (
+	// outboxBatch is how many emails a worker claims at a time and
+	// outboxLease how long it may take to send them before another worker
+	// takes over.
+	outboxBatch = 5
+	outboxLease = 2 * time.Minute
+
+	outboxPollInterval = 5 * time.Second
+	outboxDrainTimeout = 20 * time.Second
+)
+
+// startOutbox launches the workers that send queued email. They poll the
+// outbox and are woken early by queueEmail. Once ctx is cancelled each
+// worker sends what is still due before it exits, and serve waits for them
+// through app.wg.
+func (app *application) startOutbox(ctx context.Context) {
+	for i := 0; i < app.config.EmailWorkers; i++ {
+		app.wg.Add(1)
+		go func() {
+			defer app.wg.Done()
+
+			ticker := time.NewTicker(outboxPollInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					app.drainOutbox(time.Now().Add(outboxDrainTimeout))
+					return
+				case <-app.outboxWake:
+				case <-ticker.C:
+				}
+
+				for ctx.Err() == nil {
+					sent, err := app.sendQueuedEmails()
+					if err != nil {
+						app.logger.Error().Err(err).Msg("failed to send queued email")
+						break
+					}
+					if sent == 0 {
+						break
+					}
+				}
+			}
+		}()
+	}
+}
+
+// wakeOutbox lets an idle outbox worker know that an email was queued.
+func (app *application) wakeOutbox() {
+	select {
+	case app.outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+// drainOutbox keeps sending due emails until none are left or the deadline
+// has passed. Failed emails are rescheduled and so do not keep it busy.
+func (app *application) drainOutbox(deadline time.Time) {
+	for time.Now().Before(deadline) {
+		sent, err := app.sendQueuedEmails()
+		if err != nil {
+			app.logger.Error().Err(err).Msg("failed to drain outbox")
+			return
+		}
+		if sent == 0 {
+			return
+		}
+	}
+}
+
+// sendQueuedEmails claims a batch of due emails and sends them, recording
+// each result. It returns how many emails it claimed. A panic while sending
+// is returned as an error so the worker keeps running; the emails it had
+// claimed are retried once their lease runs out.
+func (app *application) sendQueuedEmails() (claimed int, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("outbox batch panicked: %v", p)
+		}
+	}()
+
+	emails, err := app.models.Outbox.Claim(outboxBatch, outboxLease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, email := range emails {
+		// Sends are not tied to the jobs context so an email that is
+		// already on its way during shutdown is not cut off.
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		sendErr := app.mailer.Send(ctx, &mailer.Message{
+			From:    email.From,
+			To:      []string{email.To},
+			Subject: email.Subject,
+			HTML:    email.HTML,
+			Text:    email.Text,
+			Headers: email.Headers,
+		})
+		cancel()
+
+		switch {
+		case sendErr == nil:
+			err = app.models.Outbox.MarkSent(email)
+		case errors.Is(sendErr, mailer.ErrSuppressed):
+			err = app.models.Outbox.MarkSuppressed(email, sendErr)
+		default:
+			err = app.models.Outbox.MarkFailed(email, sendErr, app.config.EmailMaxAttempts)
+		}
+		if err != nil {
+			return len(emails), err
+		}
+
+		if email.Status == data.EmailDead {
+			app.logger.Error().Err(sendErr).Int64("email", email.ID).Int("attempts", email.Attempts).Msg("email is dead after too many failed attempts")
+		}
+	}
+
+	return len(emails), nil
+}
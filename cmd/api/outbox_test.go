@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wdt/internal/data"
+	"github.com/wdt/internal/mailer"
+	"github.com/wdt/internal/random"
+)
+
+type panickingSender struct{}
+
+func (panickingSender) Send(ctx context.Context, msg *mailer.Message) error {
+	panic("boom")
+}
+
+func TestSendQueuedEmails_RecoversPanics(t *testing.T) {
+	app := newTestApp(t)
+	app.mailer = panickingSender{}
+
+	email := &data.OutboxEmail{
+		From:    app.config.MailFrom,
+		To:      random.RandString(10) + "@gmail.com",
+		Subject: "Hello",
+		Text:    "Hello",
+	}
+	err := app.models.Outbox.Enqueue(email)
+	require.NoError(t, err)
+
+	require.NotPanics(t, func() {
+		_, err = app.sendQueuedEmails()
+	})
+	require.ErrorContains(t, err, "panicked")
+}
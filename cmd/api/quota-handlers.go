@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/wdt/internal/data"
+)
+
+// uploadQuota is the daily upload quota of user: the override for their role
+// if there is one, the configured default otherwise.
+func (app *application) uploadQuota(user *data.User) data.UploadQuota {
+	if quota, ok := app.quotas[user.Role]; ok {
+		return quota
+	}
+	return data.UploadQuota{
+		DailyBytes: app.config.UploadDailyBytes,
+		DailyFiles: app.config.UploadDailyFiles,
+	}
+}
+
+func (app *application) getQuotaHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	since, resetsAt := data.QuotaDay(time.Now())
+
+	usage, err := app.models.Uploads.UsageSince(user.ID, since)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"quota": data.NewQuotaStatus(app.uploadQuota(user), usage, resetsAt)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+
+	validator "github.com/wdt/internal/validators"
+)
+
+func (app *application) getRecommendationsHandler(w http.ResponseWriter, r *http.Request) {
+	session := app.contextGetUser(r)
+
+	v := validator.New()
+	limit := app.readInt(r.URL.Query(), "limit", 10, v)
+	v.Check(limit > 0, "limit", "must be greater than zero")
+	v.Check(limit <= 50, "limit", "must be a maximum of 50")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	recommendations, err := app.models.Recommendations.GetForUser(session.ID, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"recommendations": recommendations}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
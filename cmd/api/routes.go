@@ -20,6 +20,7 @@ func (app *application) routes() *chi.Mux {
 	r.MethodNotAllowed(app.methodNotAllowedResponse)
 
 	r.Route("/v1/auth", func(r chi.Router) {
+		r.Get("/challenge", app.challengeHandler)
 		r.Post("/magic-link", app.registerUserWithMagicLinkHandler)
 		r.Get("/magic-link/{token}", app.authenticateUserWithMagicLinkHandler)
 		r.Delete("/logout", app.requireAuthenticatedUser(app.logoutHandler))
@@ -29,6 +30,16 @@ func (app *application) routes() *chi.Mux {
 
 	r.Route("/v1/users", func(r chi.Router) {
 		r.Get("/", app.requireAuthenticatedUser(app.getUserHandler))
+		r.Patch("/", app.requireAuthenticatedUser(app.updateUserHandler))
+		r.Get("/recommendations", app.requireAuthenticatedUser(app.getRecommendationsHandler))
+		r.Get("/quota", app.requireAuthenticatedUser(app.getQuotaHandler))
+	})
+
+	r.Post("/v1/webhooks/email", app.emailWebhookHandler)
+
+	r.Route("/v1/digest", func(r chi.Router) {
+		r.Get("/unsubscribe", app.confirmDigestUnsubscribeHandler)
+		r.Post("/unsubscribe", app.unsubscribeDigestHandler)
 	})
 
 	r.Route("/v1/favorites", func(r chi.Router) {
@@ -39,26 +50,63 @@ func (app *application) routes() *chi.Mux {
 
 	r.Route("/v1/tools", func(r chi.Router) {
 		r.Post("/", app.requireAuthenticatedUser(app.createToolHandler))
-		r.Get("/{id}", app.requireAuthenticatedUser(app.getToolHandler))
+		r.Get("/{id}", app.getToolHandler)
+		r.Get("/by-slug/{slug}", app.getToolBySlugHandler)
 		r.Delete("/{id}", app.adminPermission(app.requireAuthenticatedUser(app.deleteToolHandler)))
 		r.Patch("/{id}", app.adminPermission(app.requireAuthenticatedUser(app.updateToolHandler)))
 		r.Get("/", app.getToolsHandler)
 		r.Get("/admin", app.adminPermission(app.requireAuthenticatedUser(app.getAdminToolsHandler)))
 		r.Get("/toggle-published/{id}", app.adminPermission(app.requireAuthenticatedUser(app.toggleToolPublishedHandler)))
+		r.Get("/{id}/revisions", app.adminPermission(app.requireAuthenticatedUser(app.getToolRevisionsHandler)))
+		r.Post("/{id}/revisions/{version}/rollback", app.adminPermission(app.requireAuthenticatedUser(app.rollbackToolRevisionHandler)))
+		r.Put("/{id}/schedule", app.adminPermission(app.requireAuthenticatedUser(app.setToolScheduleHandler)))
+		r.Get("/{id}/related", app.getRelatedToolsHandler)
+		r.Get("/{id}/visit", app.visitToolHandler)
+		r.Get("/{id}/alternatives", app.getToolAlternativesHandler)
+		r.Post("/{id}/alternatives", app.adminPermission(app.requireAuthenticatedUser(app.addToolAlternativeHandler)))
+		r.Delete("/{id}/alternatives/{alternativeId}", app.adminPermission(app.requireAuthenticatedUser(app.removeToolAlternativeHandler)))
 	})
 
 	r.Route("/v1/categories", func(r chi.Router) {
 		r.Post("/", app.requireAuthenticatedUser(app.createCategoryHandler))
 		r.Get("/", app.getCategoriesHandler)
+		r.Get("/tree", app.getCategoryTreeHandler)
 		r.Get("/admin", app.adminPermission(app.requireAuthenticatedUser(app.getAdminCategoriesHandler)))
 		r.Delete("/{id}", app.adminPermission(app.requireAuthenticatedUser(app.deleteCategoryHandler)))
+		r.Patch("/{id}", app.adminPermission(app.requireAuthenticatedUser(app.updateCategoryHandler)))
+		r.Post("/{id}/merge", app.adminPermission(app.requireAuthenticatedUser(app.mergeCategoryHandler)))
+		r.Put("/order", app.adminPermission(app.requireAuthenticatedUser(app.reorderCategoriesHandler)))
 		r.Get("/toggle-published/{id}", app.adminPermission(app.requireAuthenticatedUser(app.toggleCategoryPublishedHandler)))
+		r.Put("/{id}/schedule", app.adminPermission(app.requireAuthenticatedUser(app.setCategoryScheduleHandler)))
+	})
+
+	r.Route("/v1/admin", func(r chi.Router) {
+		r.Get("/trash", app.adminPermission(app.requireAuthenticatedUser(app.getTrashHandler)))
+		r.Get("/schedule", app.adminPermission(app.requireAuthenticatedUser(app.getScheduleHandler)))
+		r.Post("/tools/bulk", app.adminPermission(app.requireAuthenticatedUser(app.bulkToolsHandler)))
+		r.Get("/tools/export", app.adminPermission(app.requireAuthenticatedUser(app.exportToolsHandler)))
+		r.Post("/tools/import", app.adminPermission(app.requireAuthenticatedUser(app.importToolsHandler)))
+		r.Post("/trash/tools/{id}/restore", app.adminPermission(app.requireAuthenticatedUser(app.restoreToolHandler)))
+		r.Post("/trash/categories/{id}/restore", app.adminPermission(app.requireAuthenticatedUser(app.restoreCategoryHandler)))
+		r.Get("/analytics", app.adminPermission(app.requireAuthenticatedUser(app.getAnalyticsHandler)))
+		r.Get("/storage", app.adminPermission(app.requireAuthenticatedUser(app.getStorageUsageHandler)))
+		r.Get("/emails", app.adminPermission(app.requireAuthenticatedUser(app.getOutboxHandler)))
+		r.Post("/emails/{id}/retry", app.adminPermission(app.requireAuthenticatedUser(app.retryEmailHandler)))
+		r.Get("/email-templates", app.adminPermission(app.requireAuthenticatedUser(app.getEmailTemplatesHandler)))
+		r.Get("/email-templates/{name}/preview", app.adminPermission(app.requireAuthenticatedUser(app.previewEmailTemplateHandler)))
 	})
 
 	r.Route("/v1/upload", func(r chi.Router) {
-		r.Post("/image", app.requireAuthenticatedUser(app.toggleCategoryPublishedHandler))
+		r.Post("/image", app.requireAuthenticatedUser(app.uploadImageHandler))
+		r.Post("/presign", app.requireAuthenticatedUser(app.presignUploadHandler))
+		r.Post("/{id}/complete", app.requireAuthenticatedUser(app.completeUploadHandler))
 	})
 
+	// The local storage backend serves its own files.
+	if files, ok := app.storage.(http.Handler); ok {
+		r.Handle("/v1/files/*", http.StripPrefix("/v1/files", files))
+	}
+
 	r.Get("/v1/healthcheck", func(w http.ResponseWriter, r *http.Request) {
 		res := map[string]string{
 			"status": "ok",
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/wdt/internal/data"
+	validator "github.com/wdt/internal/validators"
+)
+
+func (app *application) setToolScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	params := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(params, 10, 64)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		PublishAt   *time.Time `json:"publishAt"`
+		UnpublishAt *time.Time `json:"unpublishAt"`
+		Version     *int64     `json:"version"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateSchedule(v, input.PublishAt, input.UnpublishAt); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	tool, err := app.models.Tools.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !app.checkVersion(w, r, tool.ID, tool.Version, input.Version) {
+		return
+	}
+
+	tool.PublishAt = input.PublishAt
+	tool.UnpublishAt = input.UnpublishAt
+
+	err = app.models.Tools.SetSchedule(tool)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"tool": tool}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) setCategoryScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	params := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(params, 10, 64)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		PublishAt   *time.Time `json:"publishAt"`
+		UnpublishAt *time.Time `json:"unpublishAt"`
+		Version     *int64     `json:"version"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateSchedule(v, input.PublishAt, input.UnpublishAt); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	category, err := app.models.Categories.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !app.checkVersion(w, r, category.ID, category.Version, input.Version) {
+		return
+	}
+
+	category.PublishAt = input.PublishAt
+	category.UnpublishAt = input.UnpublishAt
+
+	err = app.models.Categories.SetSchedule(category)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"category": category}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	tools, err := app.models.Tools.GetScheduled()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	categories, err := app.models.Categories.GetScheduled()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"tools": tools, "categories": categories}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -19,6 +19,10 @@ func (app *application) serve() error {
 		WriteTimeout: 10 * time.Second,
 	}
 
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	app.startJobs(jobsCtx)
+
 	shutdownError := make(chan error)
 	go func() {
 		quit := make(chan os.Signal, 1)
@@ -32,7 +36,12 @@ func (app *application) serve() error {
 			shutdownError <- err
 		}
 		app.logger.Printf("completed shutdown with signal %s", s.String())
+		stopJobs()
 		app.wg.Wait()
+		err = app.flushAnalytics(context.Background())
+		if err != nil {
+			app.logger.Error().Err(err).Msg("failed to flush analytics")
+		}
 		shutdownError <- nil
 	}()
 
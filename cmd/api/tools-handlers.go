@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
@@ -16,7 +17,7 @@ func (app *application) createToolHandler(w http.ResponseWriter, r *http.Request
 		Name        string `json:"name"`
 		Category    string `json:"category"`
 		Description string `json:"description"`
-		ImageUrl    string `json:"imageUrl"`
+		UploadID    string `json:"uploadId"`
 		Website     string `json:"website"`
 	}
 
@@ -30,12 +31,22 @@ func (app *application) createToolHandler(w http.ResponseWriter, r *http.Request
 		Name:        input.Name,
 		Category:    input.Category,
 		Description: input.Description,
-		ImageUrl:    input.ImageUrl,
 		Published:   false,
 		Website:     input.Website,
 	}
 
 	v := validator.New()
+	if input.UploadID != "" {
+		upload, err := app.completedUpload(r, input.UploadID, v)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if upload != nil {
+			tool.Images = upload.Images
+		}
+	}
+
 	if data.ValidateTools(v, tool); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
@@ -72,7 +83,61 @@ func (app *application) getToolHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"tool": tool}, nil)
+	admin := app.contextGetUser(r).IsAdmin()
+	if !tool.Published && !admin {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	// Conditional re-fetches answered with 304 are not views.
+	etag := versionETag(tool.ID, tool.Version)
+	if !admin && !notModified(r, etag) {
+		app.analytics.RecordView(tool.ID)
+	}
+
+	err = app.writeCacheableJSON(w, r, envelope{"tool": tool}, etag)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getToolBySlugHandler(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	tool, err := app.models.Tools.GetBySlug(slug)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			current, err := app.models.Tools.GetRedirectSlug(slug)
+			if err != nil {
+				switch {
+				case errors.Is(err, data.ErrRecordNotFound):
+					app.notFoundResponse(w, r)
+				default:
+					app.serverErrorResponse(w, r, err)
+				}
+				return
+			}
+			http.Redirect(w, r, "/v1/tools/by-slug/"+url.PathEscape(current), http.StatusMovedPermanently)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	admin := app.contextGetUser(r).IsAdmin()
+	if !tool.Published && !admin {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	// Conditional re-fetches answered with 304 are not views.
+	etag := versionETag(tool.ID, tool.Version)
+	if !admin && !notModified(r, etag) {
+		app.analytics.RecordView(tool.ID)
+	}
+
+	err = app.writeCacheableJSON(w, r, envelope{"tool": tool}, etag)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -90,8 +155,10 @@ func (app *application) updateToolHandler(w http.ResponseWriter, r *http.Request
 		Name        *string `json:"name"`
 		Category    *string `json:"category"`
 		Description *string `json:"description"`
-		ImageUrl    *string `json:"imageUrl"`
+		UploadID    *string `json:"uploadId"`
+		Website     *string `json:"website"`
 		Published   *bool   `json:"published"`
+		Version     *int64  `json:"version"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -103,7 +170,7 @@ func (app *application) updateToolHandler(w http.ResponseWriter, r *http.Request
 	tool, err := app.models.Tools.Get(id)
 	if err != nil {
 		switch {
-		case errors.Is(err, data.ErrEditConflict):
+		case errors.Is(err, data.ErrRecordNotFound):
 			app.notFoundResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
@@ -111,6 +178,10 @@ func (app *application) updateToolHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if !app.checkVersion(w, r, tool.ID, tool.Version, input.Version) {
+		return
+	}
+
 	if input.Name != nil {
 		tool.Name = *input.Name
 	}
@@ -120,32 +191,49 @@ func (app *application) updateToolHandler(w http.ResponseWriter, r *http.Request
 	if input.Description != nil {
 		tool.Description = *input.Description
 	}
-	if input.ImageUrl != nil {
-		tool.ImageUrl = *input.ImageUrl
+	if input.Website != nil {
+		tool.Website = *input.Website
 	}
 	if input.Published != nil {
 		tool.Published = *input.Published
 	}
 
 	v := validator.New()
+	switch {
+	case input.UploadID != nil && *input.UploadID == "":
+		// An empty uploadId removes the tool's images.
+		tool.Images = data.ImageVariants{}
+	case input.UploadID != nil:
+		upload, err := app.completedUpload(r, *input.UploadID, v)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if upload != nil {
+			tool.Images = upload.Images
+		}
+	}
+
 	if data.ValidateTools(v, tool); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Tools.Update(tool)
+	err = app.models.Tools.Update(tool, app.contextGetUser(r).ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
-			v.AddError("version", "is not valid")
-			app.failedValidationResponse(w, r, v.Errors)
+			app.editConflictResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"tool": tool}, nil)
+	headers := make(http.Header)
+	headers.Set("ETag", versionETag(tool.ID, tool.Version))
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"tool": tool}, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -184,7 +272,7 @@ func (app *application) toggleToolPublishedHandler(w http.ResponseWriter, r *htt
 	tool, err := app.models.Tools.Get(id)
 	if err != nil {
 		switch {
-		case errors.Is(err, data.ErrEditConflict):
+		case errors.Is(err, data.ErrRecordNotFound):
 			app.notFoundResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
@@ -192,13 +280,17 @@ func (app *application) toggleToolPublishedHandler(w http.ResponseWriter, r *htt
 		return
 	}
 
+	if !app.checkVersion(w, r, tool.ID, tool.Version, queryVersion(r)) {
+		return
+	}
+
 	tool.Published = !tool.Published
 
-	err = app.models.Tools.Update(tool)
+	err = app.models.Tools.Update(tool, app.contextGetUser(r).ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
-			app.notFoundResponse(w, r)
+			app.editConflictResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
@@ -216,7 +308,8 @@ func (app *application) getToolsHandler(w http.ResponseWriter, r *http.Request)
 
 	var meta struct {
 		data.Filters
-		Search string
+		Search   string
+		Category string
 	}
 
 	v := validator.New()
@@ -227,8 +320,9 @@ func (app *application) getToolsHandler(w http.ResponseWriter, r *http.Request)
 	meta.Filters.Page = app.readInt(qs, "page", 1, v)
 	meta.Filters.PageSize = app.readInt(qs, "pageSize", 20, v)
 	meta.Search = app.readString(qs, "search", "")
+	meta.Category = app.readString(qs, "category", "")
 
-	tools, metadata, err := app.models.Tools.GetAllPublished(meta.Search, meta.Filters)
+	tools, metadata, err := app.models.Tools.GetAllPublished(meta.Search, meta.Category, meta.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -250,7 +344,7 @@ func (app *application) getToolsHandler(w http.ResponseWriter, r *http.Request)
 
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"tools": tools, "metadata": metadata}, nil)
+	err = app.writeCacheableJSON(w, r, envelope{"tools": tools, "metadata": metadata}, "")
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -270,8 +364,10 @@ func (app *application) getAdminToolsHandler(w http.ResponseWriter, r *http.Requ
 	meta.Filters.Page = app.readInt(qs, "page", 1, v)
 	meta.Filters.PageSize = app.readInt(qs, "pageSize", 20, v)
 	search := app.readString(qs, "search", "")
+	category := app.readString(qs, "category", "")
+	flagged := app.readBool(qs, "flagged", false, v)
 
-	tools, metadata, err := app.models.Tools.GetAll(meta.Filters, search)
+	tools, metadata, err := app.models.Tools.GetAll(meta.Filters, search, category, flagged)
 
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -1,50 +1,473 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
-	"strings"
+	"strconv"
+	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
-	"github.com/minio/minio-go/v7"
+	"github.com/wdt/internal/data"
+	"github.com/wdt/internal/images"
+	"github.com/wdt/internal/storage"
+	validator "github.com/wdt/internal/validators"
 )
 
+const (
+	maxUploadSize   = 10 << 20
+	presignedExpiry = 15 * time.Minute
+)
+
+var uploadExtensions = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpg",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
 func (app *application) uploadImageHandler(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseMultipartForm(10 << 20)
+	user := app.contextGetUser(r)
+	quota := app.uploadQuota(user)
+	since, resetsAt := data.QuotaDay(time.Now())
+
+	// Refuse before reading the body when the declared size cannot fit.
+	usage, err := app.models.Uploads.UsageSince(user.ID, since)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !quota.Allows(usage, max(r.ContentLength, 0)) {
+		app.quotaExceededResponse(w, r, resetsAt)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize+1<<20)
+
+	err = r.ParseMultipartForm(maxUploadSize)
 	if err != nil {
 		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	file, handler, err := r.FormFile("file")
+	file, _, err := r.FormFile("file")
 	if err != nil {
 		app.badRequestResponse(w, r, err)
 		return
 	}
 	defer file.Close()
 
-	c, err := app.aws.Client()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	id, err := uuid.NewUUID()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// The file never touched storage in its raw form, so there is no key.
+	// The record is reserved against the quota before anything is stored.
+	upload := &data.Upload{
+		ID:          id.String(),
+		UserID:      user.ID,
+		ContentType: http.DetectContentType(content),
+		Size:        int64(len(content)),
+		Status:      data.UploadPending,
+		ExpiresAt:   time.Now(),
+	}
+
+	err = app.models.Uploads.InsertWithinQuota(upload, quota, since)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrQuotaExceeded):
+			app.quotaExceededResponse(w, r, resetsAt)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	quarantined, err := app.scanUpload(r.Context(), upload, content)
+	if err != nil {
+		app.rejectUpload(upload)
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if quarantined {
+		app.failedValidationResponse(w, r, map[string]string{"file": "the file was flagged by the malware scanner"})
+		return
+	}
+
+	variants, err := images.Process(content)
 	if err != nil {
+		app.rejectUpload(upload)
+		switch {
+		case errors.Is(err, images.ErrUnsupportedFormat), errors.Is(err, images.ErrTooLarge):
+			app.failedValidationResponse(w, r, map[string]string{"file": err.Error()})
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	upload.Images, upload.StoredBytes, err = app.storeImageVariants(r.Context(), upload.ID, variants)
+	if err != nil {
+		app.rejectUpload(upload)
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	err = app.models.Uploads.Complete(upload)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"upload": upload, "url": upload.Images.Original(), "images": upload.Images}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// imageKey is where the variant of an upload is stored.
+func imageKey(uploadID, name, format string) string {
+	return storage.ImagesPrefix + uploadID + "/" + name + "." + images.Extension(format)
+}
+
+// uploadKeys lists every object an upload may have created.
+func uploadKeys(upload *data.Upload) []string {
+	var keys []string
+	if upload.Key != "" {
+		keys = append(keys, upload.Key)
+	}
+	for _, variant := range upload.Images {
+		keys = append(keys, imageKey(upload.ID, variant.Name, variant.Format))
+	}
+	return keys
+}
+
+// storeImageVariants puts every variant under images/{id}/ and returns them
+// with their public URLs and the number of bytes stored.
+func (app *application) storeImageVariants(ctx context.Context, id string, variants []images.Variant) (data.ImageVariants, int64, error) {
+	stored := data.ImageVariants{}
+	var size int64
+	for _, variant := range variants {
+		key := imageKey(id, variant.Name, variant.Format)
+
+		err := app.storage.Put(ctx, key, bytes.NewReader(variant.Data), int64(len(variant.Data)), variant.ContentType)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		stored = append(stored, data.ImageVariant{
+			Name:   variant.Name,
+			Format: variant.Format,
+			Width:  variant.Width,
+			Height: variant.Height,
+			URL:    app.storage.PublicURL(key),
+		})
+		size += int64(len(variant.Data))
+	}
+	return stored, size, nil
+}
+
+func (app *application) presignUploadHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		ContentType string `json:"contentType"`
+		Size        int64  `json:"size"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
 	id, err := uuid.NewUUID()
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
-	ext := strings.Split(handler.Header.Get("Content-Type"), "/")[1]
-	name := id.String() + "." + ext
-	url := "https://web-dev-tools-bucket.s3.eu-central-1.amazonaws.com/" + name
 
-	_, err = c.PutObject(r.Context(), "web-dev-tools-bucket", name, file, handler.Size, minio.PutObjectOptions{ContentType: handler.Header.Get("Content-Type")})
+	user := app.contextGetUser(r)
+	since, resetsAt := data.QuotaDay(time.Now())
+
+	upload := &data.Upload{
+		ID:          id.String(),
+		UserID:      user.ID,
+		Key:         storage.IncomingPrefix + id.String() + "." + uploadExtensions[input.ContentType],
+		ContentType: input.ContentType,
+		Size:        input.Size,
+		StoredBytes: input.Size,
+		Status:      data.UploadPending,
+		ExpiresAt:   time.Now().Add(presignedExpiry),
+	}
+
+	v := validator.New()
+	if data.ValidateUpload(v, upload, maxUploadSize); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	url, err := app.storage.PresignPut(r.Context(), upload.Key, upload.ContentType, upload.Size, presignedExpiry)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Uploads.InsertWithinQuota(upload, app.uploadQuota(user), since)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrQuotaExceeded):
+			app.quotaExceededResponse(w, r, resetsAt)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := map[string]string{
+		"Content-Type":   upload.ContentType,
+		"Content-Length": strconv.FormatInt(upload.Size, 10),
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"upload": upload, "url": url, "method": http.MethodPut, "headers": headers}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) completeUploadHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	upload, err := app.models.Uploads.Get(id.String(), app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	switch upload.Status {
+	case data.UploadComplete:
+		app.writeUploadResponse(w, r, upload)
+		return
+	case data.UploadFailed, data.UploadQuarantined:
+		app.errorResponse(w, r, http.StatusConflict, "the upload was rejected, request a new upload URL")
+		return
+	}
+
+	info, err := app.storage.Stat(r.Context(), upload.Key)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			app.failedValidationResponse(w, r, map[string]string{"upload": "the file has not been uploaded yet"})
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var problem string
+	switch {
+	case info.Size != upload.Size:
+		problem = fmt.Sprintf("expected %d bytes but %d were uploaded", upload.Size, info.Size)
+	case info.ContentType != upload.ContentType:
+		problem = fmt.Sprintf("expected content type %s but got %s", upload.ContentType, info.ContentType)
+	}
+
+	var content []byte
+	if problem == "" {
+		content, err = app.readUpload(r.Context(), upload)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if int64(len(content)) != upload.Size {
+			problem = "the file changed while it was being verified"
+		}
+	}
+
+	if problem == "" {
+		quarantined, err := app.scanUpload(r.Context(), upload, content)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if quarantined {
+			app.failedValidationResponse(w, r, map[string]string{"upload": "the file was flagged by the malware scanner"})
+			return
+		}
+	}
+
+	var variants []images.Variant
+	if problem == "" {
+		variants, err = images.Process(content)
+		switch {
+		case errors.Is(err, images.ErrUnsupportedFormat), errors.Is(err, images.ErrTooLarge):
+			problem = err.Error()
+		case err != nil:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if problem != "" {
+		app.rejectUpload(upload)
+		app.failedValidationResponse(w, r, map[string]string{"upload": problem})
+		return
+	}
+
+	upload.Images, upload.StoredBytes, err = app.storeImageVariants(r.Context(), upload.ID, variants)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
-	err = app.writeJSON(w, http.StatusCreated, envelope{"url": url}, nil)
+
+	err = app.models.Uploads.Complete(upload)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// The variants are re-encoded copies, so the raw upload is no longer needed.
+	err = app.storage.Delete(r.Context(), upload.Key)
+	if err != nil {
+		app.logError(r, err)
+	}
+
+	app.writeUploadResponse(w, r, upload)
+}
+
+// readUpload reads a raw upload back from storage, at most one byte more
+// than its declared size.
+func (app *application) readUpload(ctx context.Context, upload *data.Upload) ([]byte, error) {
+	obj, err := app.storage.Get(ctx, upload.Key)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	return io.ReadAll(io.LimitReader(obj, upload.Size+1))
+}
+
+// scanUpload runs the malware scanner over content before anything derived
+// from it is made public. Flagged files are moved under the quarantine
+// prefix and the upload is marked as quarantined.
+func (app *application) scanUpload(ctx context.Context, upload *data.Upload, content []byte) (bool, error) {
+	result, err := app.scanner.Scan(ctx, bytes.NewReader(content))
+	if err != nil {
+		return false, err
+	}
+	if result.Clean {
+		return false, nil
+	}
+
+	key := storage.QuarantinePrefix + upload.ID
+	err = app.storage.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "application/octet-stream")
+	if err != nil {
+		return false, err
+	}
+
+	if upload.Key != "" {
+		err = app.storage.Delete(ctx, upload.Key)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	err = app.models.Uploads.Quarantine(upload, key, result.Threat)
+	if err != nil {
+		return false, err
+	}
+
+	app.logger.Warn().Str("upload", upload.ID).Int64("user", upload.UserID).Str("threat", result.Threat).Msg("quarantined upload")
+	return true, nil
+}
+
+// rejectUpload marks an upload as failed and removes whatever it stored.
+func (app *application) rejectUpload(upload *data.Upload) {
+	err := app.models.Uploads.Fail(upload)
+	if err != nil {
+		app.logger.Error().Err(err).Str("upload", upload.ID).Msg("failed to mark upload as failed")
+	}
+
+	for _, key := range uploadKeys(upload) {
+		err = app.storage.Delete(context.Background(), key)
+		if err != nil {
+			app.logger.Error().Err(err).Str("upload", upload.ID).Msg("failed to delete rejected upload")
+		}
+	}
+}
+
+func (app *application) writeUploadResponse(w http.ResponseWriter, r *http.Request, upload *data.Upload) {
+	err := app.writeJSON(w, http.StatusOK, envelope{"upload": upload, "url": upload.Images.Original(), "images": upload.Images}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// completedUpload looks up an upload of the current user that a tool may
+// reference. Problems are reported on the uploadId field of v.
+func (app *application) completedUpload(r *http.Request, id string, v *validator.Validator) (*data.Upload, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		v.AddError("uploadId", "must be the id of an upload")
+		return nil, nil
+	}
+
+	upload, err := app.models.Uploads.Get(id, app.contextGetUser(r).ID)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			v.AddError("uploadId", "upload not found")
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	v.Check(upload.Status == data.UploadComplete, "uploadId", "upload has not been completed")
+	return upload, nil
+}
+
+func (app *application) getStorageUsageHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	limit := app.readInt(qs, "limit", 50, v)
+	v.Check(limit > 0 && limit <= 500, "limit", "must be between 1 and 500")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	usage, err := app.models.Uploads.UsageByOwner(limit)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"usage": usage}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
 }
@@ -1,6 +1,13 @@
 package main
 
-import "net/http"
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/wdt/internal/data"
+	validator "github.com/wdt/internal/validators"
+)
 
 func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
 	session := app.contextGetUser(r)
@@ -9,4 +16,57 @@ func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		app.badRequestResponse(w, r, err)
 	}
-}
\ No newline at end of file
+}
+
+// updateUserHandler changes the current user's preferences. The language
+// selects the locale of the emails the user receives, digest opts into the
+// weekly digest of new tools and digestCategories picks what it covers.
+func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Language         *string   `json:"language"`
+		Digest           *bool     `json:"digest"`
+		DigestCategories *[]string `json:"digestCategories"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Language != nil {
+		user.Language = *input.Language
+	}
+	if input.Digest != nil {
+		user.Digest = *input.Digest
+	}
+	if input.DigestCategories != nil {
+		user.DigestCategories = *input.DigestCategories
+	}
+
+	v := validator.New()
+	v.Check(user.Language == "" || validator.PermittedValue(user.Language, app.templates.Locales()...), "language", "must be one of "+strings.Join(app.templates.Locales(), ", "))
+	data.ValidateDigestCategories(v, user.DigestCategories)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Users.UpdatePreferences(user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
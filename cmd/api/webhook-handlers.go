@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/wdt/internal/data"
+	"github.com/wdt/internal/mailer"
+)
+
+// emailWebhookHandler receives delivery events from the mail provider.
+// Bounces and complaints are recorded against the address. A permanent
+// bounce suppresses all further email to it and a complaint ends the
+// weekly digest for the user. Other events are acknowledged and ignored.
+func (app *application) emailWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	err = mailer.VerifyWebhook(app.config.EmailWebhookKey, r.Header, body, time.Now())
+	if err != nil {
+		app.errorResponse(w, r, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var event struct {
+		Type string `json:"type"`
+		Data struct {
+			To     []string `json:"to"`
+			Bounce struct {
+				Type    string `json:"type"`
+				SubType string `json:"subType"`
+				Message string `json:"message"`
+			} `json:"bounce"`
+		} `json:"data"`
+	}
+
+	err = json.Unmarshal(body, &event)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var eventType string
+	switch event.Type {
+	case "email.bounced":
+		eventType = data.EmailEventBounce
+	case "email.complained":
+		eventType = data.EmailEventComplaint
+	default:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	for _, recipient := range event.Data.To {
+		emailEvent := &data.EmailEvent{
+			WebhookID:  r.Header.Get("svix-id"),
+			Email:      recipient,
+			Type:       eventType,
+			BounceType: event.Data.Bounce.Type,
+			Detail:     event.Data.Bounce.Message,
+		}
+
+		recorded, err := app.models.Suppressions.RecordEvent(emailEvent)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if !recorded {
+			continue
+		}
+
+		switch {
+		case eventType == data.EmailEventBounce && emailEvent.BounceType == "Permanent":
+			err = app.models.Suppressions.Suppress(emailEvent.Email, "hard bounce")
+		case eventType == data.EmailEventComplaint && emailEvent.UserID != 0:
+			err = app.models.Digests.Unsubscribe(emailEvent.UserID)
+			if errors.Is(err, data.ErrRecordNotFound) {
+				err = nil
+			}
+		}
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		app.logger.Info().Str("email", emailEvent.Email).Str("type", eventType).Str("bounceType", emailEvent.BounceType).Msg("recorded email event")
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -16,6 +16,56 @@ type AppConfig struct {
 	GithubClientSecret string `mapstructure:"GITHUB_CLIENT_SECRET"`
 	AwsAccessKey       string `mapstructure:"AWS_ACCESS_KEY"`
 	AwsSecretKey       string `mapstructure:"AWS_SECRET_KEY"`
+	TrashRetentionDays int    `mapstructure:"TRASH_RETENTION_DAYS"`
+
+	RelatedCategoryWeight  float64 `mapstructure:"RELATED_CATEGORY_WEIGHT"`
+	RelatedFavoritesWeight float64 `mapstructure:"RELATED_FAVORITES_WEIGHT"`
+	RelatedTextWeight      float64 `mapstructure:"RELATED_TEXT_WEIGHT"`
+
+	LinkCheckConcurrency   int `mapstructure:"LINK_CHECK_CONCURRENCY"`
+	LinkCheckIntervalHours int `mapstructure:"LINK_CHECK_INTERVAL_HOURS"`
+	LinkCheckFlagDays      int `mapstructure:"LINK_CHECK_FLAG_DAYS"`
+
+	StorageBackend    string `mapstructure:"STORAGE_BACKEND"`
+	StorageLocalDir   string `mapstructure:"STORAGE_LOCAL_DIR"`
+	StoragePublicURL  string `mapstructure:"STORAGE_PUBLIC_URL"`
+	StorageSigningKey string `mapstructure:"STORAGE_SIGNING_KEY"`
+	S3Endpoint        string `mapstructure:"S3_ENDPOINT"`
+	S3Bucket          string `mapstructure:"S3_BUCKET"`
+	S3Region          string `mapstructure:"S3_REGION"`
+	S3UseSSL          bool   `mapstructure:"S3_USE_SSL"`
+	S3PathStyle       bool   `mapstructure:"S3_PATH_STYLE"`
+	UploadGraceHours  int    `mapstructure:"UPLOAD_GRACE_HOURS"`
+	UploadDailyBytes  int64  `mapstructure:"UPLOAD_DAILY_BYTES"`
+	UploadDailyFiles  int64  `mapstructure:"UPLOAD_DAILY_FILES"`
+	UploadRoleQuotas  string `mapstructure:"UPLOAD_ROLE_QUOTAS"`
+	Scanner           string `mapstructure:"SCANNER"`
+	ClamdAddress      string `mapstructure:"CLAMD_ADDRESS"`
+
+	MailBackend  string `mapstructure:"MAIL_BACKEND"`
+	MailFrom     string `mapstructure:"MAIL_FROM"`
+	MailDropDir  string `mapstructure:"MAIL_DROP_DIR"`
+	SMTPHost     string `mapstructure:"SMTP_HOST"`
+	SMTPPort     int    `mapstructure:"SMTP_PORT"`
+	SMTPUsername string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword string `mapstructure:"SMTP_PASSWORD"`
+	SMTPStartTLS bool   `mapstructure:"SMTP_STARTTLS"`
+
+	EmailWorkers     int    `mapstructure:"EMAIL_WORKERS"`
+	EmailMaxAttempts int    `mapstructure:"EMAIL_MAX_ATTEMPTS"`
+	EmailWebhookKey  string `mapstructure:"EMAIL_WEBHOOK_SECRET"`
+
+	TokenSecret   string `mapstructure:"TOKEN_SECRET"`
+	DigestEnabled bool   `mapstructure:"DIGEST_ENABLED"`
+
+	MagicLinkEmailCooldown int    `mapstructure:"MAGIC_LINK_EMAIL_COOLDOWN_SECONDS"`
+	MagicLinkEmailHourly   int    `mapstructure:"MAGIC_LINK_EMAIL_HOURLY"`
+	MagicLinkIPHourly      int    `mapstructure:"MAGIC_LINK_IP_HOURLY"`
+	Challenge              string `mapstructure:"CHALLENGE"`
+	ChallengeDifficulty    int    `mapstructure:"CHALLENGE_POW_DIFFICULTY"`
+	CaptchaVerifyURL       string `mapstructure:"CAPTCHA_VERIFY_URL"`
+	CaptchaSecret          string `mapstructure:"CAPTCHA_SECRET"`
+	CaptchaSiteKey         string `mapstructure:"CAPTCHA_SITE_KEY"`
 }
 
 func LoadConfig(path string) (AppConfig, error) {
@@ -28,6 +78,41 @@ func LoadConfig(path string) (AppConfig, error) {
 	viper.SetConfigType("env")
 	viper.AutomaticEnv()
 
+	viper.SetDefault("TRASH_RETENTION_DAYS", 30)
+	viper.SetDefault("RELATED_CATEGORY_WEIGHT", 1.0)
+	viper.SetDefault("RELATED_FAVORITES_WEIGHT", 1.0)
+	viper.SetDefault("RELATED_TEXT_WEIGHT", 0.5)
+	viper.SetDefault("LINK_CHECK_CONCURRENCY", 8)
+	viper.SetDefault("LINK_CHECK_INTERVAL_HOURS", 24)
+	viper.SetDefault("LINK_CHECK_FLAG_DAYS", 3)
+	viper.SetDefault("STORAGE_BACKEND", "s3")
+	viper.SetDefault("STORAGE_LOCAL_DIR", "./uploads")
+	viper.SetDefault("S3_ENDPOINT", "s3.eu-central-1.amazonaws.com")
+	viper.SetDefault("S3_BUCKET", "web-dev-tools-bucket")
+	viper.SetDefault("S3_REGION", "eu-central-1")
+	viper.SetDefault("S3_USE_SSL", true)
+	viper.SetDefault("S3_PATH_STYLE", false)
+	viper.SetDefault("UPLOAD_GRACE_HOURS", 24)
+	viper.SetDefault("UPLOAD_DAILY_BYTES", 100<<20)
+	viper.SetDefault("UPLOAD_DAILY_FILES", 50)
+	viper.SetDefault("UPLOAD_ROLE_QUOTAS", "admin=0/0")
+	viper.SetDefault("SCANNER", "none")
+	viper.SetDefault("CLAMD_ADDRESS", "localhost:3310")
+	viper.SetDefault("MAIL_BACKEND", "resend")
+	viper.SetDefault("MAIL_FROM", "Web Dev Tools <info@web-dev-tools.xyz>")
+	viper.SetDefault("MAIL_DROP_DIR", "./mail")
+	viper.SetDefault("SMTP_PORT", 587)
+	viper.SetDefault("SMTP_STARTTLS", true)
+	viper.SetDefault("EMAIL_WORKERS", 4)
+	viper.SetDefault("EMAIL_MAX_ATTEMPTS", 8)
+	viper.SetDefault("DIGEST_ENABLED", false)
+	viper.SetDefault("MAGIC_LINK_EMAIL_COOLDOWN_SECONDS", 60)
+	viper.SetDefault("MAGIC_LINK_EMAIL_HOURLY", 5)
+	viper.SetDefault("MAGIC_LINK_IP_HOURLY", 20)
+	viper.SetDefault("CHALLENGE", "none")
+	viper.SetDefault("CHALLENGE_POW_DIFFICULTY", 18)
+	viper.SetDefault("CAPTCHA_VERIFY_URL", "https://challenges.cloudflare.com/turnstile/v0/siteverify")
+
 	if err := viper.ReadInConfig(); err != nil {
 		var configFileNotFoundError viper.ConfigFileNotFoundError
 		if errors.As(err, &configFileNotFoundError) {
@@ -40,5 +125,9 @@ func LoadConfig(path string) (AppConfig, error) {
 		return AppConfig{}, err
 	}
 
+	if config.TrashRetentionDays < 0 {
+		return AppConfig{}, fmt.Errorf("TRASH_RETENTION_DAYS must not be negative")
+	}
+
 	return config, nil
 }
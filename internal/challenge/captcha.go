@@ -0,0 +1,69 @@
+package challenge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// TurnstileURL is the siteverify endpoint of Cloudflare Turnstile.
+// hCaptcha and reCAPTCHA expose the same API under their own URLs.
+const TurnstileURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+
+// Captcha verifies captcha responses against a siteverify endpoint.
+type Captcha struct {
+	URL     string
+	Secret  string
+	SiteKey string
+	Client  *http.Client
+}
+
+func (c *Captcha) Issue() (*Challenge, error) {
+	return &Challenge{Type: "captcha", SiteKey: c.SiteKey}, nil
+}
+
+func (c *Captcha) Verify(ctx context.Context, proof, subject, remoteIP string) error {
+	if proof == "" {
+		return ErrFailed
+	}
+
+	form := url.Values{"secret": {c.Secret}, "response": {proof}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("challenge: siteverify returned %s", resp.Status)
+	}
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return err
+	}
+	if !result.Success {
+		return ErrFailed
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// Package challenge verifies that a request came from a client willing to
+// prove it is not a bot, either by spending CPU on a proof of work or by
+// solving a captcha.
+package challenge
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrFailed = errors.New("challenge: verification failed")
+
+// Verifier checks the proof a client sent with a request. Subject is what
+// the request acts on, such as an email address, and remoteIP the client's
+// address. Verify returns ErrFailed when the proof is missing or wrong.
+type Verifier interface {
+	Verify(ctx context.Context, proof, subject, remoteIP string) error
+}
+
+// Issuer is implemented by verifiers that hand out a challenge for the
+// client to solve before sending a proof.
+type Issuer interface {
+	Issue() (*Challenge, error)
+}
+
+// Challenge describes what a client has to do to produce a proof.
+type Challenge struct {
+	Type       string `json:"type"`
+	Challenge  string `json:"challenge,omitempty"`
+	Difficulty int    `json:"difficulty,omitempty"`
+	SiteKey    string `json:"siteKey,omitempty"`
+}
+
+// None accepts every request.
+type None struct{}
+
+func (None) Verify(ctx context.Context, proof, subject, remoteIP string) error {
+	return nil
+}
+
+func (None) Issue() (*Challenge, error) {
+	return &Challenge{Type: "none"}, nil
+}
+
+// Stub accepts exactly one proof. It is meant for tests and local
+// development.
+type Stub struct {
+	Proof string
+}
+
+func (s Stub) Verify(ctx context.Context, proof, subject, remoteIP string) error {
+	if s.Proof == "" || proof != s.Proof {
+		return ErrFailed
+	}
+	return nil
+}
+
+func (s Stub) Issue() (*Challenge, error) {
+	return &Challenge{Type: "stub"}, nil
+}
@@ -0,0 +1,102 @@
+package challenge
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProofOfWork(t *testing.T) {
+	now := time.Now()
+	p := NewProofOfWork([]byte("secret"), 8, time.Minute)
+	p.now = func() time.Time { return now }
+
+	c, err := p.Issue()
+	require.NoError(t, err)
+	require.Equal(t, "pow", c.Type)
+	expiring, err := p.Issue()
+	require.NoError(t, err)
+
+	// The proof is bound to its subject.
+	proof := Solve(c.Challenge, "a@example.com", c.Difficulty)
+	require.ErrorIs(t, p.Verify(context.Background(), proof, "b@example.com", ""), ErrFailed)
+
+	// A solved challenge is good for one request.
+	require.NoError(t, p.Verify(context.Background(), proof, "a@example.com", ""))
+	require.ErrorIs(t, p.Verify(context.Background(), proof, "a@example.com", ""), ErrFailed)
+
+	// Challenges must be signed with the key.
+	other := NewProofOfWork([]byte("other"), 8, time.Minute)
+	require.ErrorIs(t, other.Verify(context.Background(), proof, "a@example.com", ""), ErrFailed)
+
+	payload, _, _ := strings.Cut(c.Challenge, ".")
+	require.ErrorIs(t, p.Verify(context.Background(), payload+".AAAA:1", "a@example.com", ""), ErrFailed)
+	require.ErrorIs(t, p.Verify(context.Background(), "", "a@example.com", ""), ErrFailed)
+
+	proof = Solve(expiring.Challenge, "a@example.com", expiring.Difficulty)
+	now = now.Add(2 * time.Minute)
+	require.ErrorIs(t, p.Verify(context.Background(), proof, "a@example.com", ""), ErrFailed)
+}
+
+func TestProofOfWork_ForgetsExpiredChallenges(t *testing.T) {
+	now := time.Now()
+	p := NewProofOfWork([]byte("secret"), 4, time.Minute)
+	p.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		c, err := p.Issue()
+		require.NoError(t, err)
+		require.NoError(t, p.Verify(context.Background(), Solve(c.Challenge, "a@example.com", c.Difficulty), "a@example.com", ""))
+	}
+	require.Len(t, p.spent, 3)
+
+	now = now.Add(2 * time.Minute)
+	c, err := p.Issue()
+	require.NoError(t, err)
+	require.NoError(t, p.Verify(context.Background(), Solve(c.Challenge, "a@example.com", c.Difficulty), "a@example.com", ""))
+	require.Len(t, p.spent, 1)
+}
+
+func TestLeadingZeros(t *testing.T) {
+	require.Equal(t, 0, LeadingZeros([]byte{0x80}))
+	require.Equal(t, 12, LeadingZeros([]byte{0x00, 0x0f}))
+	require.Equal(t, 16, LeadingZeros([]byte{0x00, 0x00}))
+}
+
+func TestCaptcha(t *testing.T) {
+	forms := make(chan url.Values, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		select {
+		case forms <- r.PostForm:
+		default:
+		}
+		if r.PostForm.Get("response") == "good" {
+			w.Write([]byte(`{"success": true}`))
+		} else {
+			w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+		}
+	}))
+	defer srv.Close()
+
+	c := &Captcha{URL: srv.URL, Secret: "secret"}
+	require.NoError(t, c.Verify(context.Background(), "good", "a@example.com", "10.0.0.1"))
+	form := <-forms
+	require.Equal(t, "secret", form.Get("secret"))
+	require.Equal(t, "10.0.0.1", form.Get("remoteip"))
+	require.ErrorIs(t, c.Verify(context.Background(), "bad", "a@example.com", "10.0.0.1"), ErrFailed)
+	require.ErrorIs(t, c.Verify(context.Background(), "", "a@example.com", "10.0.0.1"), ErrFailed)
+}
+
+func TestStub(t *testing.T) {
+	s := Stub{Proof: "ok"}
+	require.NoError(t, s.Verify(context.Background(), "ok", "a@example.com", ""))
+	require.ErrorIs(t, s.Verify(context.Background(), "nope", "a@example.com", ""), ErrFailed)
+	require.ErrorIs(t, Stub{}.Verify(context.Background(), "", "a@example.com", ""), ErrFailed)
+}
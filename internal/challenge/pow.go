@@ -0,0 +1,147 @@
+package challenge
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProofOfWork is a hashcash-style verifier. It issues signed challenges
+// that expire after TTL, so issuing keeps no state on the server. A proof is
+// "<challenge>:<counter>" where the SHA-256 hash of
+// "<challenge>:<subject>:<counter>" starts with Difficulty zero bits.
+// Binding the subject keeps a solved challenge from being reused for other
+// subjects, and each challenge is remembered as spent until it expires so a
+// solve is only good for one request. Spent challenges are kept in memory,
+// so with several instances a proof can be replayed once per instance.
+type ProofOfWork struct {
+	Key        []byte
+	Difficulty int
+	TTL        time.Duration
+
+	now func() time.Time
+
+	mu      sync.Mutex
+	spent   map[string]time.Time
+	sweptAt time.Time
+}
+
+func NewProofOfWork(key []byte, difficulty int, ttl time.Duration) *ProofOfWork {
+	return &ProofOfWork{
+		Key:        key,
+		Difficulty: difficulty,
+		TTL:        ttl,
+		now:        time.Now,
+		spent:      make(map[string]time.Time),
+	}
+}
+
+func (p *ProofOfWork) Issue() (*Challenge, error) {
+	nonce := make([]byte, 16)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := strconv.FormatInt(p.now().Add(p.TTL).Unix(), 10) + "-" + hex.EncodeToString(nonce)
+	challenge := payload + "." + base64.RawURLEncoding.EncodeToString(p.sign(payload))
+
+	return &Challenge{Type: "pow", Challenge: challenge, Difficulty: p.Difficulty}, nil
+}
+
+func (p *ProofOfWork) Verify(ctx context.Context, proof, subject, remoteIP string) error {
+	challenge, counter, ok := strings.Cut(proof, ":")
+	if !ok || counter == "" {
+		return ErrFailed
+	}
+
+	payload, sig, ok := strings.Cut(challenge, ".")
+	if !ok {
+		return ErrFailed
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, p.sign(payload)) {
+		return ErrFailed
+	}
+
+	expires, _, _ := strings.Cut(payload, "-")
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || p.now().After(time.Unix(unix, 0)) {
+		return ErrFailed
+	}
+
+	if LeadingZeros(Hash(challenge, subject, counter)) < p.Difficulty {
+		return ErrFailed
+	}
+
+	if !p.spend(challenge, time.Unix(unix, 0)) {
+		return ErrFailed
+	}
+	return nil
+}
+
+// spend marks challenge as used until it expires and reports whether it
+// was still unused. Expired entries are dropped at most once per TTL.
+func (p *ProofOfWork) spend(challenge string, expires time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	if now.Sub(p.sweptAt) >= p.TTL {
+		for c, exp := range p.spent {
+			if now.After(exp) {
+				delete(p.spent, c)
+			}
+		}
+		p.sweptAt = now
+	}
+
+	if _, ok := p.spent[challenge]; ok {
+		return false
+	}
+	p.spent[challenge] = expires
+	return true
+}
+
+func (p *ProofOfWork) sign(payload string) []byte {
+	h := hmac.New(sha256.New, p.Key)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
+// Hash returns the hash a proof of work is checked against.
+func Hash(challenge, subject, counter string) []byte {
+	sum := sha256.Sum256([]byte(challenge + ":" + subject + ":" + counter))
+	return sum[:]
+}
+
+// LeadingZeros counts the leading zero bits of b.
+func LeadingZeros(b []byte) int {
+	n := 0
+	for _, c := range b {
+		if c != 0 {
+			return n + bits.LeadingZeros8(c)
+		}
+		n += 8
+	}
+	return n
+}
+
+// Solve finds a proof for challenge and subject. Clients do the same work;
+// it is here for tests and tooling.
+func Solve(challenge, subject string, difficulty int) string {
+	for i := 0; ; i++ {
+		counter := strconv.Itoa(i)
+		if LeadingZeros(Hash(challenge, subject, counter)) >= difficulty {
+			return challenge + ":" + counter
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	validator "github.com/wdt/internal/validators"
+)
+
+const (
+	AnalyticsByTool     = "tool"
+	AnalyticsByCategory = "category"
+)
+
+// AnalyticsKey identifies one daily counter row. Day is truncated to UTC
+// midnight.
+type AnalyticsKey struct {
+	ToolID int64
+	Day    time.Time
+}
+
+type AnalyticsCounts struct {
+	Views  int64
+	Clicks int64
+}
+
+// AnalyticsBuffer collects view and click counts in memory so a busy tool
+// page costs one upsert per flush instead of one per request. Nothing about
+// the visitor is kept, only the counts.
+type AnalyticsBuffer struct {
+	mu     sync.Mutex
+	counts map[AnalyticsKey]AnalyticsCounts
+}
+
+func NewAnalyticsBuffer() *AnalyticsBuffer {
+	return &AnalyticsBuffer{counts: make(map[AnalyticsKey]AnalyticsCounts)}
+}
+
+func (b *AnalyticsBuffer) RecordView(toolID int64) {
+	b.add(AnalyticsKey{ToolID: toolID, Day: analyticsDay(time.Now())}, AnalyticsCounts{Views: 1})
+}
+
+func (b *AnalyticsBuffer) RecordClick(toolID int64) {
+	b.add(AnalyticsKey{ToolID: toolID, Day: analyticsDay(time.Now())}, AnalyticsCounts{Clicks: 1})
+}
+
+func (b *AnalyticsBuffer) add(key AnalyticsKey, counts AnalyticsCounts) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	current := b.counts[key]
+	current.Views += counts.Views
+	current.Clicks += counts.Clicks
+	b.counts[key] = current
+}
+
+// Drain returns everything recorded so far and empties the buffer.
+func (b *AnalyticsBuffer) Drain() map[AnalyticsKey]AnalyticsCounts {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	counts := b.counts
+	b.counts = make(map[AnalyticsKey]AnalyticsCounts)
+	return counts
+}
+
+// Restore puts drained counts back, for when writing them out failed.
+func (b *AnalyticsBuffer) Restore(counts map[AnalyticsKey]AnalyticsCounts) {
+	for key, c := range counts {
+		b.add(key, c)
+	}
+}
+
+func analyticsDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+type AnalyticsPoint struct {
+	Day    string `json:"day"`
+	Views  int64  `json:"views"`
+	Clicks int64  `json:"clicks"`
+}
+
+// AnalyticsSeries is the daily history of one tool or one category.
+type AnalyticsSeries struct {
+	ToolID int64            `json:"toolId,omitempty"`
+	Name   string           `json:"name"`
+	Views  int64            `json:"views"`
+	Clicks int64            `json:"clicks"`
+	Points []AnalyticsPoint `json:"points"`
+}
+
+type AnalyticsFilter struct {
+	GroupBy  string
+	From     time.Time
+	To       time.Time
+	ToolID   int64
+	Category string
+	Limit    int
+}
+
+func ValidateAnalyticsFilter(v *validator.Validator, f AnalyticsFilter) {
+	v.Check(validator.PermittedValue(f.GroupBy, AnalyticsByTool, AnalyticsByCategory), "groupBy", "must be tool or category")
+	v.Check(!f.To.Before(f.From), "to", "must not be before from")
+	v.Check(f.To.Sub(f.From) <= 366*24*time.Hour, "to", "must be at most a year after from")
+	v.Check(f.Limit > 0, "limit", "must be greater than zero")
+	v.Check(f.Limit <= 100, "limit", "must be a maximum of 100")
+}
+
+type AnalyticsModel struct {
+	DB *sql.DB
+}
+
+// Flush adds the buffered counts to the daily counters in a single
+// statement. Counts for tools that were purged in the meantime are dropped.
+func (m AnalyticsModel) Flush(counts map[AnalyticsKey]AnalyticsCounts) error {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	toolIDs := make([]int64, 0, len(counts))
+	days := make([]string, 0, len(counts))
+	views := make([]int64, 0, len(counts))
+	clicks := make([]int64, 0, len(counts))
+	for key, c := range counts {
+		toolIDs = append(toolIDs, key.ToolID)
+		days = append(days, key.Day.Format("2006-01-02"))
+		views = append(views, c.Views)
+		clicks = append(clicks, c.Clicks)
+	}
+
+	query := `INSERT INTO tool_stats_daily (tool_id, day, views, clicks)
+			  SELECT u.tool_id, u.day, u.views, u.clicks
+			  FROM unnest($1::bigint[], $2::date[], $3::bigint[], $4::bigint[]) AS u(tool_id, day, views, clicks)
+			  WHERE EXISTS (SELECT 1 FROM tools t WHERE t.id = u.tool_id)
+			  ON CONFLICT (tool_id, day) DO UPDATE
+			  SET views = tool_stats_daily.views + EXCLUDED.views, clicks = tool_stats_daily.clicks + EXCLUDED.clicks`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, pq.Array(toolIDs), pq.Array(days), pq.Array(views), pq.Array(clicks))
+	return err
+}
+
+// Series returns daily views and clicks between from and to, inclusive,
+// grouped per tool or per category. Only the limit series with the most views
+// are returned, busiest first. Days without traffic are left out.
+func (m AnalyticsModel) Series(f AnalyticsFilter) ([]*AnalyticsSeries, error) {
+	key, name := "t.id", "t.name"
+	if f.GroupBy == AnalyticsByCategory {
+		key, name = "0::bigint", "t.category"
+	}
+
+	query := fmt.Sprintf(`SELECT tool_id, name, day, views, clicks
+			  FROM (
+				  SELECT *, dense_rank() OVER (ORDER BY total DESC, name, tool_id) AS series_rank
+				  FROM (
+					  SELECT %[1]s AS tool_id, %[2]s AS name, s.day, sum(s.views) AS views, sum(s.clicks) AS clicks,
+							 sum(sum(s.views)) OVER (PARTITION BY %[1]s, %[2]s) AS total
+					  FROM tool_stats_daily s
+					  INNER JOIN tools t ON t.id = s.tool_id
+					  WHERE s.day BETWEEN $1 AND $2
+					  AND ($3::bigint = 0 OR t.id = $3)
+					  AND ($4 = '' OR t.category = $4)
+					  GROUP BY %[1]s, %[2]s, s.day
+				  ) daily
+			  ) ranked
+			  WHERE series_rank <= $5
+			  ORDER BY series_rank, day`, key, name)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, f.From.Format("2006-01-02"), f.To.Format("2006-01-02"), f.ToolID, f.Category, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	series := []*AnalyticsSeries{}
+	var current *AnalyticsSeries
+	for rows.Next() {
+		var (
+			toolID int64
+			name   string
+			day    time.Time
+			point  AnalyticsPoint
+		)
+		err := rows.Scan(&toolID, &name, &day, &point.Views, &point.Clicks)
+		if err != nil {
+			return nil, err
+		}
+
+		if current == nil || current.ToolID != toolID || current.Name != name {
+			current = &AnalyticsSeries{ToolID: toolID, Name: name, Points: []AnalyticsPoint{}}
+			series = append(series, current)
+		}
+
+		point.Day = day.Format("2006-01-02")
+		current.Points = append(current.Points, point)
+		current.Views += point.Views
+		current.Clicks += point.Clicks
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return series, nil
+}
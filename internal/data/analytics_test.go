@@ -0,0 +1,53 @@
+package data
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestAnalyticsBuffer(t *testing.T) {
+	buffer := NewAnalyticsBuffer()
+	buffer.RecordView(1)
+	buffer.RecordView(1)
+	buffer.RecordClick(1)
+	buffer.RecordView(2)
+
+	counts := buffer.Drain()
+	require.Len(t, counts, 2)
+
+	key := AnalyticsKey{ToolID: 1, Day: analyticsDay(time.Now())}
+	require.Equal(t, AnalyticsCounts{Views: 2, Clicks: 1}, counts[key])
+	require.Empty(t, buffer.Drain())
+
+	buffer.RecordClick(1)
+	buffer.Restore(counts)
+	require.Equal(t, AnalyticsCounts{Views: 2, Clicks: 2}, buffer.Drain()[key])
+}
+
+func TestAnalyticsModel_FlushSeries(t *testing.T) {
+	tool := CreateTool(t)
+	day := analyticsDay(time.Now())
+
+	buffer := NewAnalyticsBuffer()
+	buffer.RecordView(tool.ID)
+	buffer.RecordClick(tool.ID)
+	require.NoError(t, testQueries.Analytics.Flush(buffer.Drain()))
+
+	buffer.RecordView(tool.ID)
+	require.NoError(t, testQueries.Analytics.Flush(buffer.Drain()))
+
+	series, err := testQueries.Analytics.Series(AnalyticsFilter{
+		GroupBy: AnalyticsByTool,
+		From:    day,
+		To:      day,
+		ToolID:  tool.ID,
+		Limit:   10,
+	})
+	require.NoError(t, err)
+	require.Len(t, series, 1)
+	require.Equal(t, tool.ID, series[0].ToolID)
+	require.Equal(t, int64(2), series[0].Views)
+	require.Equal(t, int64(1), series[0].Clicks)
+	require.Len(t, series[0].Points, 1)
+}
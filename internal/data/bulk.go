@@ -0,0 +1,143 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	validator "github.com/wdt/internal/validators"
+)
+
+const (
+	BulkPublish      = "publish"
+	BulkUnpublish    = "unpublish"
+	BulkDelete       = "delete"
+	BulkRecategorize = "recategorize"
+)
+
+const (
+	BulkStatusUpdated   = "updated"
+	BulkStatusUnchanged = "unchanged"
+	BulkStatusNotFound  = "not_found"
+	BulkStatusInvalid   = "invalid"
+)
+
+var ErrUnknownCategory = errors.New("unknown category")
+
+type BulkToolAction struct {
+	IDs      []int64
+	Action   string
+	Category string
+	DryRun   bool
+}
+
+type BulkResult struct {
+	ID     int64             `json:"id"`
+	Status string            `json:"status"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+func ValidateBulkToolAction(v *validator.Validator, action BulkToolAction) {
+	v.Check(len(action.IDs) > 0, "ids", "must contain at least one id")
+	v.Check(len(action.IDs) <= 500, "ids", "must not contain more than 500 ids")
+	v.Check(validator.Unique(action.IDs), "ids", "must not contain duplicate values")
+	v.Check(validator.PermittedValue(action.Action, BulkPublish, BulkUnpublish, BulkDelete, BulkRecategorize), "action", "invalid action")
+
+	if action.Action == BulkRecategorize {
+		v.Check(action.Category != "", "category", "must be provided")
+	}
+}
+
+// Bulk applies one action to many tools inside a single transaction. Each
+// tool gets its own result; tools that are missing or would become invalid
+// are skipped without affecting the rest. With DryRun set the transaction is
+// rolled back, so the results show what would happen without changing data.
+// Recategorizing into a category that is missing or in the trash fails with
+// ErrUnknownCategory before any tool is touched.
+func (m ToolModel) Bulk(action BulkToolAction, editorID int64) ([]BulkResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if action.Action == BulkRecategorize {
+		var exists bool
+		err = tx.QueryRowContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM categories WHERE name = $1 AND deleted_at IS NULL)`,
+			action.Category).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrUnknownCategory
+		}
+	}
+
+	results := make([]BulkResult, 0, len(action.IDs))
+
+	for _, id := range action.IDs {
+		result := BulkResult{ID: id, Status: BulkStatusUpdated}
+
+		tool, err := lockTool(ctx, tx, id)
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				result.Status = BulkStatusNotFound
+				results = append(results, result)
+				continue
+			}
+			return nil, err
+		}
+
+		if action.Action == BulkDelete {
+			err = deleteTool(ctx, tx, id)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, result)
+			continue
+		}
+
+		changed := false
+		switch action.Action {
+		case BulkPublish:
+			changed = !tool.Published
+			tool.Published = true
+		case BulkUnpublish:
+			changed = tool.Published
+			tool.Published = false
+		case BulkRecategorize:
+			changed = tool.Category != action.Category
+			tool.Category = action.Category
+		}
+
+		if !changed {
+			result.Status = BulkStatusUnchanged
+			results = append(results, result)
+			continue
+		}
+
+		v := validator.New()
+		if ValidateTools(v, tool); !v.Valid() {
+			result.Status = BulkStatusInvalid
+			result.Errors = v.Errors
+			results = append(results, result)
+			continue
+		}
+
+		err = updateTool(ctx, tx, tool, editorID)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	if action.DryRun {
+		return results, nil
+	}
+
+	return results, tx.Commit()
+}
@@ -0,0 +1,77 @@
+package data
+
+import (
+	"github.com/stretchr/testify/require"
+	validator "github.com/wdt/internal/validators"
+	"testing"
+)
+
+func TestValidateBulkToolAction(t *testing.T) {
+	v := validator.New()
+	ValidateBulkToolAction(v, BulkToolAction{IDs: []int64{1, 1}, Action: BulkRecategorize})
+	require.Contains(t, v.Errors, "ids")
+	require.Contains(t, v.Errors, "category")
+
+	v = validator.New()
+	ValidateBulkToolAction(v, BulkToolAction{IDs: []int64{1, 2}, Action: BulkPublish})
+	require.True(t, v.Valid())
+}
+
+func TestToolModel_Bulk(t *testing.T) {
+	first := CreateTool(t)
+	second := CreateTool(t)
+
+	results, err := testQueries.Tools.Bulk(BulkToolAction{
+		IDs:    []int64{first.ID, second.ID, 0},
+		Action: BulkPublish,
+	}, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	require.Equal(t, BulkStatusUpdated, results[0].Status)
+	require.Equal(t, BulkStatusUpdated, results[1].Status)
+	require.Equal(t, BulkStatusNotFound, results[2].Status)
+
+	dbTool, err := testQueries.Tools.Get(first.ID)
+	require.NoError(t, err)
+	require.True(t, dbTool.Published)
+}
+
+func TestToolModel_Bulk_DryRun(t *testing.T) {
+	tool := CreateTool(t)
+	category := CreateCategory(t)
+
+	results, err := testQueries.Tools.Bulk(BulkToolAction{
+		IDs:      []int64{tool.ID},
+		Action:   BulkRecategorize,
+		Category: category.Name,
+		DryRun:   true,
+	}, 0)
+	require.NoError(t, err)
+	require.Equal(t, BulkStatusUpdated, results[0].Status)
+
+	dbTool, err := testQueries.Tools.Get(tool.ID)
+	require.NoError(t, err)
+	require.Equal(t, tool.Category, dbTool.Category)
+}
+
+func TestToolModel_Bulk_UnknownCategory(t *testing.T) {
+	tool := CreateTool(t)
+
+	_, err := testQueries.Tools.Bulk(BulkToolAction{
+		IDs:      []int64{tool.ID},
+		Action:   BulkRecategorize,
+		Category: "does-not-exist",
+	}, 0)
+	require.ErrorIs(t, err, ErrUnknownCategory)
+
+	category := CreateCategory(t)
+	err = testQueries.Categories.Delete(category.ID)
+	require.NoError(t, err)
+
+	_, err = testQueries.Tools.Bulk(BulkToolAction{
+		IDs:      []int64{tool.ID},
+		Action:   BulkRecategorize,
+		Category: category.Name,
+	}, 0)
+	require.ErrorIs(t, err, ErrUnknownCategory)
+}
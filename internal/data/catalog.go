@@ -0,0 +1,197 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	validator "github.com/wdt/internal/validators"
+)
+
+const (
+	ImportStatusCreated   = "created"
+	ImportStatusUpdated   = "updated"
+	ImportStatusUnchanged = "unchanged"
+	ImportStatusInvalid   = "invalid"
+)
+
+// CatalogEntry is one tool as it appears in an exported or imported catalog.
+// On import the ID is ignored and existing tools are matched by website first
+// and slug second. Optional fields are pointers so that a row which leaves a
+// column or key out keeps the existing value instead of clearing it. Images
+// belong to uploads in one environment's bucket, so they are not part of the
+// catalog.
+type CatalogEntry struct {
+	ID          int64   `json:"id,omitempty"`
+	Name        string  `json:"name"`
+	Slug        string  `json:"slug"`
+	Category    *string `json:"category"`
+	Description *string `json:"description"`
+	Website     *string `json:"website"`
+	Published   *bool   `json:"published"`
+}
+
+func (t *Tool) CatalogEntry() CatalogEntry {
+	category, description, website := t.Category, t.Description, t.Website
+	published := t.Published
+
+	return CatalogEntry{
+		ID:          t.ID,
+		Name:        t.Name,
+		Slug:        t.Slug,
+		Category:    &category,
+		Description: &description,
+		Website:     &website,
+		Published:   &published,
+	}
+}
+
+type ImportResult struct {
+	Row    int               `json:"row"`
+	ID     int64             `json:"id,omitempty"`
+	Status string            `json:"status"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// Export streams every tool that is not in the trash to fn, ordered by id.
+func (m ToolModel) Export(ctx context.Context, fn func(tool *Tool) error) error {
+	query := `SELECT id, created_at, name, slug, category, description, published, website, version
+			  FROM tools
+			  WHERE deleted_at IS NULL
+			  ORDER BY id`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tool Tool
+
+		err := rows.Scan(
+			&tool.ID,
+			&tool.CreatedAt,
+			&tool.Name,
+			&tool.Slug,
+			&tool.Category,
+			&tool.Description,
+			&tool.Published,
+			&tool.Website,
+			&tool.Version,
+		)
+		if err != nil {
+			return err
+		}
+
+		err = fn(&tool)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// Import upserts rows inside a single transaction and reports the outcome of
+// each one. Invalid rows are skipped. With dryRun set the transaction is
+// rolled back, so the report shows what would happen without changing data.
+func (m ToolModel) Import(rows []CatalogEntry, dryRun bool, editorID int64) ([]ImportResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	results := make([]ImportResult, 0, len(rows))
+
+	for i, row := range rows {
+		result := ImportResult{Row: i + 1}
+
+		tool, err := findImportTarget(ctx, tx, row)
+		if err != nil {
+			return nil, err
+		}
+
+		existing := tool != nil
+		if !existing {
+			tool = &Tool{}
+		}
+		before := snapshotTool(tool)
+
+		tool.Name = row.Name
+		if row.Category != nil {
+			tool.Category = *row.Category
+		}
+		if row.Description != nil {
+			tool.Description = *row.Description
+		}
+		if row.Website != nil {
+			tool.Website = *row.Website
+		}
+		if row.Published != nil {
+			tool.Published = *row.Published
+		}
+
+		v := validator.New()
+		if ValidateTools(v, tool); !v.Valid() {
+			result.Status = ImportStatusInvalid
+			result.Errors = v.Errors
+			results = append(results, result)
+			continue
+		}
+
+		switch {
+		case !existing:
+			err = insertTool(ctx, tx, tool)
+			result.Status = ImportStatusCreated
+		case len(diffSnapshots(before, snapshotTool(tool))) == 0:
+			result.Status = ImportStatusUnchanged
+		default:
+			err = updateTool(ctx, tx, tool, editorID)
+			result.Status = ImportStatusUpdated
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		result.ID = tool.ID
+		results = append(results, result)
+	}
+
+	if dryRun {
+		return results, nil
+	}
+
+	return results, tx.Commit()
+}
+
+func findImportTarget(ctx context.Context, q querier, row CatalogEntry) (*Tool, error) {
+	query := `SELECT id
+			  FROM tools
+			  WHERE deleted_at IS NULL AND ((website = $1 AND $1 <> '') OR (slug = $2 AND $2 <> ''))
+			  ORDER BY (website = $1) DESC
+			  LIMIT 1`
+
+	website := ""
+	if row.Website != nil {
+		website = *row.Website
+	}
+
+	var id int64
+	err := q.QueryRowContext(ctx, query, website, row.Slug).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil
+		default:
+			return nil, err
+		}
+	}
+
+	return lockTool(ctx, q, id)
+}
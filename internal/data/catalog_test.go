@@ -0,0 +1,79 @@
+package data
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/wdt/internal/random"
+	"testing"
+)
+
+func TestToolModel_Export(t *testing.T) {
+	tool := CreateTool(t)
+
+	found := false
+	err := testQueries.Tools.Export(context.Background(), func(exported *Tool) error {
+		if exported.ID == tool.ID {
+			found = true
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.True(t, found)
+}
+
+func TestToolModel_Import(t *testing.T) {
+	existing := CreateTool(t)
+	website := "https://" + random.RandString(10) + ".dev"
+
+	rows := []CatalogEntry{
+		{Name: random.RandString(10), Category: stringPtr("DB"), Website: &website},
+		{Name: existing.Name, Slug: existing.Slug, Category: &existing.Category, Description: stringPtr("imported")},
+		{Name: "", Category: stringPtr("DB")},
+	}
+
+	results, err := testQueries.Tools.Import(rows, false, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	require.Equal(t, ImportStatusCreated, results[0].Status)
+	require.Equal(t, ImportStatusUpdated, results[1].Status)
+	require.Equal(t, existing.ID, results[1].ID)
+	require.Equal(t, ImportStatusInvalid, results[2].Status)
+
+	results, err = testQueries.Tools.Import(rows[:1], false, 0)
+	require.NoError(t, err)
+	require.Equal(t, ImportStatusUnchanged, results[0].Status)
+}
+
+func TestToolModel_Import_DryRun(t *testing.T) {
+	website := "https://" + random.RandString(10) + ".dev"
+	rows := []CatalogEntry{{Name: random.RandString(10), Category: stringPtr("DB"), Website: &website}}
+
+	results, err := testQueries.Tools.Import(rows, true, 0)
+	require.NoError(t, err)
+	require.Equal(t, ImportStatusCreated, results[0].Status)
+
+	results, err = testQueries.Tools.Import(rows, true, 0)
+	require.NoError(t, err)
+	require.Equal(t, ImportStatusCreated, results[0].Status)
+}
+
+func TestToolModel_Import_KeepsMissingFields(t *testing.T) {
+	existing := CreateTool(t)
+
+	rows := []CatalogEntry{{Name: existing.Name + " renamed", Slug: existing.Slug}}
+
+	results, err := testQueries.Tools.Import(rows, false, 0)
+	require.NoError(t, err)
+	require.Equal(t, ImportStatusUpdated, results[0].Status)
+
+	dbTool, err := testQueries.Tools.Get(existing.ID)
+	require.NoError(t, err)
+	require.Equal(t, existing.Name+" renamed", dbTool.Name)
+	require.Equal(t, existing.Category, dbTool.Category)
+	require.Equal(t, existing.Description, dbTool.Description)
+	require.Equal(t, existing.Website, dbTool.Website)
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
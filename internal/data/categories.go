@@ -10,65 +10,125 @@ import (
 	validator "github.com/wdt/internal/validators"
 )
 
+var (
+	ErrCategoryInUse         = errors.New("category in use")
+	ErrDuplicateCategoryName = errors.New("duplicate category name")
+	ErrCategoryCycle         = errors.New("category cycle")
+)
+
 type CategoryModel struct {
 	DB *sql.DB
 }
 
 type Category struct {
-	ID        int64     `json:"id"`
-	CreatedAt time.Time `json:"createdAt,omitempty"`
-	Name      string    `json:"name"`
-	Published bool      `json:"published"`
-	Version   int64     `json:"version,omitempty"`
+	ID          int64       `json:"id"`
+	CreatedAt   time.Time   `json:"createdAt,omitempty"`
+	Name        string      `json:"name"`
+	ParentID    int64       `json:"parentId,omitempty"`
+	Description string      `json:"description"`
+	Icon        string      `json:"icon,omitempty"`
+	Position    int         `json:"position"`
+	ToolCount   int         `json:"toolCount"`
+	Published   bool        `json:"published"`
+	Version     int64       `json:"version,omitempty"`
+	PublishAt   *time.Time  `json:"publishAt,omitempty"`
+	UnpublishAt *time.Time  `json:"unpublishAt,omitempty"`
+	DeletedAt   *time.Time  `json:"deletedAt,omitempty"`
+	Children    []*Category `json:"children,omitempty"`
 }
 
 func ValidateCategories(v *validator.Validator, category *Category) {
 	v.Check(category.Name != "", "name", "must be provided")
-	v.Check(len(category.Name) <= 500, "name", "must not be more than 500 bytes long")
+	v.Check(len(category.Name) <= 40, "name", "must not be more than 40 bytes long")
+	v.Check(len(category.Description) <= 500, "description", "must not be more than 500 bytes long")
+	v.Check(len(category.Icon) <= 200, "icon", "must not be more than 200 bytes long")
+	v.Check(category.Position >= 0, "position", "must not be negative")
+	v.Check(category.ParentID >= 0, "parentId", "must not be negative")
+	v.Check(category.ID == 0 || category.ParentID != category.ID, "parentId", "must not be the category itself")
 }
 
 func (m CategoryModel) Insert(category *Category) error {
-	query := `INSERT INTO categories (name)
-			 VALUES ($1)
-			 RETURNING id, created_at, version
+	query := `INSERT INTO categories (name, parent_id, description, icon, position)
+			 VALUES ($1, $2, $3, $4, COALESCE((SELECT max(position) FROM categories), 0) + 1)
+			 RETURNING id, created_at, version, position
 			`
 
 	args := []interface{}{
 		category.Name,
+		newNullInt64(category.ParentID),
+		category.Description,
+		category.Icon,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
+	if category.ParentID != 0 {
+		_, err := getCategoryID(ctx, m.DB, category.ParentID)
+		if err != nil {
+			return err
+		}
+	}
+
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
 		&category.ID,
 		&category.CreatedAt,
 		&category.Version,
+		&category.Position,
 	)
 
 	if err != nil {
-		return err
+		switch {
+		case isDuplicateCategoryName(err):
+			return ErrDuplicateCategoryName
+		default:
+			return err
+		}
 	}
 
 	return nil
 }
 
 func (m CategoryModel) Get(id int64) (*Category, error) {
-	query := `SELECT id, created_at, name, published, version
-			  FROM categories
-			  WHERE id = $1`
-
-	var category Category
+	query := `SELECT id, created_at, name, coalesce(parent_id, 0), description, icon, position, published, version, publish_at, unpublish_at,
+			  (SELECT count(*) FROM tools t WHERE t.category = c.name AND t.deleted_at IS NULL)
+			  FROM categories c
+			  WHERE id = $1 AND deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+	return getCategory(ctx, m.DB, query, id)
+}
+
+// lockCategory reads a category and holds a row lock on it until the
+// surrounding transaction ends.
+func lockCategory(ctx context.Context, q querier, id int64) (*Category, error) {
+	query := `SELECT id, created_at, name, coalesce(parent_id, 0), description, icon, position, published, version, publish_at, unpublish_at,
+			  (SELECT count(*) FROM tools t WHERE t.category = c.name AND t.deleted_at IS NULL)
+			  FROM categories c
+			  WHERE id = $1 AND deleted_at IS NULL
+			  FOR UPDATE`
+
+	return getCategory(ctx, q, query, id)
+}
+
+func getCategory(ctx context.Context, q querier, query string, args ...any) (*Category, error) {
+	var category Category
+
+	err := q.QueryRowContext(ctx, query, args...).Scan(
 		&category.ID,
 		&category.CreatedAt,
 		&category.Name,
+		&category.ParentID,
+		&category.Description,
+		&category.Icon,
+		&category.Position,
 		&category.Published,
 		&category.Version,
+		&category.PublishAt,
+		&category.UnpublishAt,
+		&category.ToolCount,
 	)
 
 	if err != nil {
@@ -85,8 +145,10 @@ func (m CategoryModel) Get(id int64) (*Category, error) {
 }
 
 func (m CategoryModel) GetAll(filters Filters) ([]*Category, Metadata, error) {
-	query := fmt.Sprintf(`SELECT count(*) OVER(), id, name, published, created_at
-			  FROM categories
+	query := fmt.Sprintf(`SELECT count(*) OVER(), id, name, coalesce(parent_id, 0), description, icon, position, published, created_at, version,
+			  (SELECT count(*) FROM tools t WHERE t.category = c.name AND t.deleted_at IS NULL)
+			  FROM categories c
+			  WHERE deleted_at IS NULL
 			  ORDER BY %s %s
 			  LIMIT $1 OFFSET $2`,
 		filters.sortColumn(), filters.sortDirection())
@@ -109,8 +171,14 @@ func (m CategoryModel) GetAll(filters Filters) ([]*Category, Metadata, error) {
 			&totalRecords,
 			&category.ID,
 			&category.Name,
+			&category.ParentID,
+			&category.Description,
+			&category.Icon,
+			&category.Position,
 			&category.Published,
 			&category.CreatedAt,
+			&category.Version,
+			&category.ToolCount,
 		)
 		if err != nil {
 			return nil, Metadata{}, err
@@ -127,19 +195,71 @@ func (m CategoryModel) GetAll(filters Filters) ([]*Category, Metadata, error) {
 	return categories, metadata, nil
 }
 
-func (m CategoryModel) Update(category *Category) error {
-	query := `UPDATE categories SET name = $1, published = $2, version = version + 1 WHERE id = $3`
+// Update saves category. Tools are linked to categories by name, so a rename
+// moves every tool along to the new name, recording a revision attributed to
+// editorID for each of them.
+func (m CategoryModel) Update(category *Category, editorID int64) error {
+	query := `UPDATE categories
+			  SET name = $1, parent_id = $2, description = $3, icon = $4, position = $5, published = $6, version = version + 1
+			  WHERE id = $7 AND version = $8 AND deleted_at IS NULL
+			  RETURNING version`
 
 	args := []interface{}{
 		category.Name,
+		newNullInt64(category.ParentID),
+		category.Description,
+		category.Icon,
+		category.Position,
 		category.Published,
 		category.ID,
+		category.Version,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	_, err := m.DB.ExecContext(ctx, query, args...)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	previous, err := lockCategory(ctx, tx, category.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRecordNotFound):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	if previous.Name != category.Name {
+		taken, err := categoryNameTaken(ctx, tx, category.Name, category.ID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrDuplicateCategoryName
+		}
+	}
+
+	if previous.ParentID != category.ParentID && category.ParentID != 0 {
+		_, err = getCategoryID(ctx, tx, category.ParentID)
+		if err != nil {
+			return err
+		}
+
+		cycle, err := isDescendant(ctx, tx, category.ID, category.ParentID)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return ErrCategoryCycle
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&category.Version)
 
 	if err != nil {
 		switch {
@@ -150,29 +270,310 @@ func (m CategoryModel) Update(category *Category) error {
 		}
 	}
 
-	return nil
+	if previous.Name != category.Name {
+		_, err = moveTools(ctx, tx, previous.Name, category.Name, editorID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func getCategoryID(ctx context.Context, q querier, id int64) (int64, error) {
+	query := `SELECT id FROM categories WHERE id = $1 AND deleted_at IS NULL`
+
+	err := q.QueryRowContext(ctx, query, id).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return id, nil
+}
+
+// isDescendant reports whether id is ancestorID itself or sits anywhere
+// below it in the category tree.
+func isDescendant(ctx context.Context, q querier, ancestorID, id int64) (bool, error) {
+	query := `WITH RECURSIVE subtree AS (
+				  SELECT id FROM categories WHERE id = $1
+				  UNION
+				  SELECT c.id FROM categories c INNER JOIN subtree s ON c.parent_id = s.id
+			  )
+			  SELECT EXISTS (SELECT 1 FROM subtree WHERE id = $2)`
+
+	var found bool
+	err := q.QueryRowContext(ctx, query, ancestorID, id).Scan(&found)
+	return found, err
+}
+
+// isDuplicateCategoryName reports whether err comes from the unique index on
+// live category names.
+func isDuplicateCategoryName(err error) bool {
+	return err.Error() == "pq: duplicate key value violates unique constraint \"categories_name_key\""
+}
+
+func categoryNameTaken(ctx context.Context, q querier, name string, id int64) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM categories WHERE name = $1 AND id <> $2 AND deleted_at IS NULL)`
+
+	var taken bool
+	err := q.QueryRowContext(ctx, query, name, id).Scan(&taken)
+	return taken, err
+}
+
+// moveTools recategorizes every tool in category from to category to and
+// returns how many were moved.
+func moveTools(ctx context.Context, tx *sql.Tx, from, to string, editorID int64) (int, error) {
+	query := `SELECT id FROM tools WHERE category = $1 AND deleted_at IS NULL ORDER BY id`
+
+	rows, err := tx.QueryContext(ctx, query, from)
+	if err != nil {
+		return 0, err
+	}
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		tool, err := lockTool(ctx, tx, id)
+		if err != nil {
+			return 0, err
+		}
+
+		tool.Category = to
+		err = updateTool(ctx, tx, tool, editorID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(ids), nil
+}
+
+// Merge moves every tool from the source category into the target category
+// and then moves the source to the trash. It returns the number of tools
+// moved.
+func (m CategoryModel) Merge(sourceID, targetID int64, editorID int64) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	source, err := lockCategory(ctx, tx, sourceID)
+	if err != nil {
+		return 0, err
+	}
+
+	target, err := lockCategory(ctx, tx, targetID)
+	if err != nil {
+		return 0, err
+	}
+
+	moved, err := moveTools(ctx, tx, source.Name, target.Name, editorID)
+	if err != nil {
+		return 0, err
+	}
+
+	// The source's subcategories move under the target. A target nested
+	// inside the source is lifted to the source's place first so it does not
+	// end up below one of its own descendants.
+	nested, err := isDescendant(ctx, tx, source.ID, target.ID)
+	if err != nil {
+		return 0, err
+	}
+	if nested {
+		_, err = tx.ExecContext(ctx, `UPDATE categories SET parent_id = $1, version = version + 1 WHERE id = $2`, newNullInt64(source.ParentID), target.ID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE categories SET parent_id = $1, version = version + 1 WHERE parent_id = $2 AND id <> $1`, target.ID, source.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE categories SET deleted_at = NOW() WHERE id = $1`, source.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return moved, tx.Commit()
 }
 
+// Reorder sets the position of each category to its index in ids. Categories
+// not listed keep their position.
+func (m CategoryModel) Reorder(ids []int64) error {
+	query := `UPDATE categories SET position = $1, version = version + 1 WHERE id = $2 AND deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, id := range ids {
+		result, err := tx.ExecContext(ctx, query, i+1, id)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Delete moves a category to the trash. It stays restorable until
+// PurgeDeleted removes it for good. Categories that still have tools are
+// refused with ErrCategoryInUse; use Merge to reassign the tools first.
 func (m CategoryModel) Delete(id int64) error {
-	query := `DELETE FROM categories WHERE id = $1`
+	query := `UPDATE categories SET deleted_at = NOW() WHERE id = $1`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	_, err := m.DB.ExecContext(ctx, query, id)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	category, err := lockCategory(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if category.ToolCount > 0 {
+		return ErrCategoryInUse
+	}
 
+	_, err = tx.ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
 
+	return tx.Commit()
+}
+
+func (m CategoryModel) Restore(id int64) error {
+	query := `UPDATE categories SET deleted_at = NULL, version = version + 1 WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		switch {
+		case isDuplicateCategoryName(err):
+			return ErrDuplicateCategoryName
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
 	return nil
 }
 
-func (m CategoryModel) GetAllPublished() ([]*Category, error) {
-	query := `SELECT id, name
+// GetDeleted lists the categories in the trash, most recently deleted first.
+func (m CategoryModel) GetDeleted() ([]*Category, error) {
+	query := `SELECT id, created_at, name, published, version, deleted_at
 			  FROM categories
-			  WHERE published = true
-			  ORDER BY name
+			  WHERE deleted_at IS NOT NULL
+			  ORDER BY deleted_at DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []*Category{}
+
+	for rows.Next() {
+		var category Category
+		err := rows.Scan(
+			&category.ID,
+			&category.CreatedAt,
+			&category.Name,
+			&category.Published,
+			&category.Version,
+			&category.DeletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		categories = append(categories, &category)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+// PurgeDeleted permanently removes categories that have been in the trash
+// for longer than retention and returns how many were removed.
+func (m CategoryModel) PurgeDeleted(retention time.Duration) (int64, error) {
+	query := `DELETE FROM categories WHERE deleted_at < NOW() - make_interval(secs => $1)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, retention.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (m CategoryModel) GetAllPublished() ([]*Category, error) {
+	query := `SELECT id, name, coalesce(parent_id, 0), description, icon, position,
+			  (SELECT count(*) FROM tools t WHERE t.category = c.name AND t.published = true AND t.deleted_at IS NULL)
+			  FROM categories c
+			  WHERE published = true AND deleted_at IS NULL
+			  ORDER BY position, name
 			 `
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -191,6 +592,11 @@ func (m CategoryModel) GetAllPublished() ([]*Category, error) {
 		err := rows.Scan(
 			&category.ID,
 			&category.Name,
+			&category.ParentID,
+			&category.Description,
+			&category.Icon,
+			&category.Position,
+			&category.ToolCount,
 		)
 		if err != nil {
 			return nil, err
@@ -205,3 +611,25 @@ func (m CategoryModel) GetAllPublished() ([]*Category, error) {
 
 	return categories, nil
 }
+
+// BuildCategoryTree nests categories under their parents, keeping the order
+// they were given in. Categories whose parent is not in the list become roots.
+func BuildCategoryTree(categories []*Category) []*Category {
+	byID := make(map[int64]*Category, len(categories))
+	for _, category := range categories {
+		category.Children = nil
+		byID[category.ID] = category
+	}
+
+	roots := []*Category{}
+	for _, category := range categories {
+		parent, ok := byID[category.ParentID]
+		if !ok || category.ParentID == category.ID {
+			roots = append(roots, category)
+			continue
+		}
+		parent.Children = append(parent.Children, category)
+	}
+
+	return roots
+}
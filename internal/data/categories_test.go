@@ -2,6 +2,7 @@ package data
 
 import (
 	"github.com/stretchr/testify/require"
+	"github.com/wdt/internal/random"
 	"testing"
 )
 
@@ -51,7 +52,7 @@ func TestCategoryModel_Update(t *testing.T) {
 
 	category.Name = "Updated Name"
 
-	err := testQueries.Categories.Update(&category)
+	err := testQueries.Categories.Update(&category, 0)
 	require.NoError(t, err)
 
 	dbCategory, err := testQueries.Categories.Get(category.ID)
@@ -86,3 +87,181 @@ func TestCategoryModel_GetAllPublished(t *testing.T) {
 		require.NotEmpty(t, category)
 	}
 }
+
+func TestCategoryModel_Update_Conflict(t *testing.T) {
+	category := CreateCategory(t)
+	stale := category
+
+	category.Name = "First Edit"
+	err := testQueries.Categories.Update(&category, 0)
+	require.NoError(t, err)
+	require.Equal(t, stale.Version+1, category.Version)
+
+	stale.Name = "Second Edit"
+	err = testQueries.Categories.Update(&stale, 0)
+	require.ErrorIs(t, err, ErrEditConflict)
+}
+
+func TestCategoryModel_Delete_NotFound(t *testing.T) {
+	err := testQueries.Categories.Delete(0)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestCategoryModel_Restore(t *testing.T) {
+	category := CreateCategory(t)
+
+	err := testQueries.Categories.Delete(category.ID)
+	require.NoError(t, err)
+
+	err = testQueries.Categories.Restore(category.ID)
+	require.NoError(t, err)
+
+	dbCategory, err := testQueries.Categories.Get(category.ID)
+	require.NoError(t, err)
+	require.Equal(t, category.Name, dbCategory.Name)
+}
+
+func TestCategoryModel_Update_RenameMovesTools(t *testing.T) {
+	category := CreateCategory(t)
+	tool := CreateTool(t)
+	tool.Category = category.Name
+	err := testQueries.Tools.Update(&tool, 0)
+	require.NoError(t, err)
+
+	category.Name = random.RandString(10)
+	err = testQueries.Categories.Update(&category, 0)
+	require.NoError(t, err)
+
+	dbTool, err := testQueries.Tools.Get(tool.ID)
+	require.NoError(t, err)
+	require.Equal(t, category.Name, dbTool.Category)
+}
+
+func TestCategoryModel_Update_DuplicateName(t *testing.T) {
+	first := CreateCategory(t)
+	second := CreateCategory(t)
+
+	second.Name = first.Name
+	err := testQueries.Categories.Update(&second, 0)
+	require.ErrorIs(t, err, ErrDuplicateCategoryName)
+}
+
+func TestCategoryModel_Insert_DuplicateName(t *testing.T) {
+	existing := CreateCategory(t)
+
+	err := testQueries.Categories.Insert(&Category{Name: existing.Name})
+	require.ErrorIs(t, err, ErrDuplicateCategoryName)
+}
+
+func TestCategoryModel_Restore_DuplicateName(t *testing.T) {
+	trashed := CreateCategory(t)
+	err := testQueries.Categories.Delete(trashed.ID)
+	require.NoError(t, err)
+
+	err = testQueries.Categories.Insert(&Category{Name: trashed.Name})
+	require.NoError(t, err)
+
+	err = testQueries.Categories.Restore(trashed.ID)
+	require.ErrorIs(t, err, ErrDuplicateCategoryName)
+}
+
+func TestCategoryModel_Delete_InUse(t *testing.T) {
+	category := CreateCategory(t)
+	tool := CreateTool(t)
+	tool.Category = category.Name
+	err := testQueries.Tools.Update(&tool, 0)
+	require.NoError(t, err)
+
+	err = testQueries.Categories.Delete(category.ID)
+	require.ErrorIs(t, err, ErrCategoryInUse)
+}
+
+func TestCategoryModel_Merge(t *testing.T) {
+	source := CreateCategory(t)
+	target := CreateCategory(t)
+	tool := CreateTool(t)
+	tool.Category = source.Name
+	err := testQueries.Tools.Update(&tool, 0)
+	require.NoError(t, err)
+
+	moved, err := testQueries.Categories.Merge(source.ID, target.ID, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, moved)
+
+	dbTool, err := testQueries.Tools.Get(tool.ID)
+	require.NoError(t, err)
+	require.Equal(t, target.Name, dbTool.Category)
+
+	_, err = testQueries.Categories.Get(source.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	dbTarget, err := testQueries.Categories.Get(target.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, dbTarget.ToolCount)
+}
+
+func TestCategoryModel_Reorder(t *testing.T) {
+	first := CreateCategory(t)
+	second := CreateCategory(t)
+
+	err := testQueries.Categories.Reorder([]int64{second.ID, first.ID})
+	require.NoError(t, err)
+
+	dbFirst, err := testQueries.Categories.Get(first.ID)
+	require.NoError(t, err)
+	dbSecond, err := testQueries.Categories.Get(second.ID)
+	require.NoError(t, err)
+	require.Less(t, dbSecond.Position, dbFirst.Position)
+}
+
+func TestCategoryModel_Update_Cycle(t *testing.T) {
+	parent := CreateCategory(t)
+	child := CreateCategory(t)
+	child.ParentID = parent.ID
+	err := testQueries.Categories.Update(&child, 0)
+	require.NoError(t, err)
+
+	dbParent, err := testQueries.Categories.Get(parent.ID)
+	require.NoError(t, err)
+	dbParent.ParentID = child.ID
+	err = testQueries.Categories.Update(dbParent, 0)
+	require.ErrorIs(t, err, ErrCategoryCycle)
+
+	dbParent.ParentID = parent.ID
+	err = testQueries.Categories.Update(dbParent, 0)
+	require.ErrorIs(t, err, ErrCategoryCycle)
+}
+
+func TestCategoryModel_Merge_ReparentsChildren(t *testing.T) {
+	source := CreateCategory(t)
+	target := CreateCategory(t)
+	child := CreateCategory(t)
+	child.ParentID = source.ID
+	err := testQueries.Categories.Update(&child, 0)
+	require.NoError(t, err)
+
+	_, err = testQueries.Categories.Merge(source.ID, target.ID, 0)
+	require.NoError(t, err)
+
+	dbChild, err := testQueries.Categories.Get(child.ID)
+	require.NoError(t, err)
+	require.Equal(t, target.ID, dbChild.ParentID)
+}
+
+func TestBuildCategoryTree(t *testing.T) {
+	categories := []*Category{
+		{ID: 1, Name: "root"},
+		{ID: 2, Name: "child", ParentID: 1},
+		{ID: 3, Name: "grandchild", ParentID: 2},
+		{ID: 4, Name: "orphan", ParentID: 99},
+	}
+
+	roots := BuildCategoryTree(categories)
+	require.Len(t, roots, 2)
+	require.Equal(t, int64(1), roots[0].ID)
+	require.Equal(t, int64(4), roots[1].ID)
+	require.Len(t, roots[0].Children, 1)
+	require.Equal(t, int64(2), roots[0].Children[0].ID)
+	require.Len(t, roots[0].Children[0].Children, 1)
+	require.Equal(t, int64(3), roots[0].Children[0].Children[0].ID)
+}
@@ -0,0 +1,164 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// DigestRecipient is a user who opted into the weekly digest. Since is the
+// end of the period covered by their previous digest.
+type DigestRecipient struct {
+	UserID   int64
+	Email    string
+	Name     string
+	Language string
+	Since    time.Time
+}
+
+// DigestTool is a tool listed in a digest.
+type DigestTool struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Slug        string    `json:"slug"`
+	Category    string    `json:"category"`
+	Description string    `json:"description"`
+	Website     string    `json:"website"`
+	PublishedAt time.Time `json:"publishedAt"`
+}
+
+type DigestModel struct {
+	DB *sql.DB
+}
+
+// GetDue returns up to limit opted-in users whose previous digest is at
+// least interval old, longest waiting first.
+func (m DigestModel) GetDue(interval time.Duration, limit int) ([]*DigestRecipient, error) {
+	query := `SELECT id, email, COALESCE(name, ''), language, last_digest_at
+			  FROM users
+			  WHERE digest AND last_digest_at <= NOW() - make_interval(secs => $1)
+			  ORDER BY last_digest_at
+			  LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, interval.Seconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipients []*DigestRecipient
+	for rows.Next() {
+		var r DigestRecipient
+		err = rows.Scan(&r.UserID, &r.Email, &r.Name, &r.Language, &r.Since)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, &r)
+	}
+
+	return recipients, rows.Err()
+}
+
+// NewTools returns up to limit tools published after since and no later
+// than until, newest first. They are limited to the categories the user
+// chose for the digest and their subcategories. Users who chose none get the
+// categories of the tools they favorited, and users without favorites get
+// every new tool.
+func (m DigestModel) NewTools(userID int64, since, until time.Time, limit int) ([]*DigestTool, error) {
+	query := `WITH RECURSIVE chosen_names AS (
+				  SELECT unnest(digest_categories) AS name FROM users WHERE id = $1
+			  ), interest_names AS (
+				  SELECT name FROM chosen_names
+				  UNION
+				  SELECT t.category FROM favorites f INNER JOIN tools t ON t.id = f.tool_id
+				  WHERE f.user_id = $1 AND NOT EXISTS (SELECT 1 FROM chosen_names)
+			  ), interest_categories AS (
+				  SELECT id, name FROM categories WHERE name IN (SELECT name FROM interest_names) AND deleted_at IS NULL
+				  UNION
+				  SELECT c.id, c.name FROM categories c INNER JOIN interest_categories ic ON c.parent_id = ic.id WHERE c.deleted_at IS NULL
+			  )
+			  SELECT id, name, slug, category, description, website, published_at
+			  FROM tools
+			  WHERE published AND deleted_at IS NULL AND published_at > $2 AND published_at <= $3
+			  AND (NOT EXISTS (SELECT 1 FROM interest_names)
+			       OR category IN (SELECT name FROM interest_names)
+			       OR category IN (SELECT name FROM interest_categories))
+			  ORDER BY published_at DESC, id DESC
+			  LIMIT $4`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, since, until, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tools []*DigestTool
+	for rows.Next() {
+		var tool DigestTool
+		err = rows.Scan(&tool.ID, &tool.Name, &tool.Slug, &tool.Category, &tool.Description, &tool.Website, &tool.PublishedAt)
+		if err != nil {
+			return nil, err
+		}
+		tools = append(tools, &tool)
+	}
+
+	return tools, rows.Err()
+}
+
+// MarkSent ends the recipient's digest period at until. It returns
+// ErrEditConflict when the period was already handled or the user opted out
+// in the meantime.
+func (m DigestModel) MarkSent(recipient *DigestRecipient, until time.Time) error {
+	query := `UPDATE users
+			  SET last_digest_at = $1
+			  WHERE id = $2 AND digest AND last_digest_at = $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, until, recipient.UserID, recipient.Since)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrEditConflict
+	}
+
+	return nil
+}
+
+// Unsubscribe turns the digest off for the user.
+func (m DigestModel) Unsubscribe(userID int64) error {
+	query := `UPDATE users
+			  SET digest = false, version = version + 1
+			  WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package data
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/wdt/internal/random"
+	"testing"
+	"time"
+)
+
+func createPublishedTool(t *testing.T, category string) Tool {
+	tool := &Tool{
+		Name:        random.RandString(10),
+		Category:    category,
+		Description: random.RandString(20),
+		Published:   true,
+	}
+
+	err := testQueries.Tools.Insert(tool)
+	require.NoError(t, err)
+
+	return *tool
+}
+
+func digestToolIDs(tools []*DigestTool) []int64 {
+	ids := make([]int64, len(tools))
+	for i, tool := range tools {
+		ids[i] = tool.ID
+	}
+	return ids
+}
+
+func TestDigestModel_NewTools(t *testing.T) {
+	category := random.RandString(8)
+	user := CreateRandomUser(t)
+	favorite := createPublishedTool(t, category)
+	err := testQueries.Favorites.AddFavorite(user.ID, favorite.ID)
+	require.NoError(t, err)
+
+	since := time.Now().Add(-time.Minute)
+	matching := createPublishedTool(t, category)
+	other := createPublishedTool(t, random.RandString(8))
+	unpublished := CreateTool(t)
+	until := time.Now().Add(time.Minute)
+
+	tools, err := testQueries.Digests.NewTools(user.ID, since, until, 1000)
+	require.NoError(t, err)
+	ids := digestToolIDs(tools)
+	require.Contains(t, ids, matching.ID)
+	require.NotContains(t, ids, other.ID)
+	require.NotContains(t, ids, unpublished.ID)
+
+	// Without favorites every new tool is listed.
+	newcomer := CreateRandomUser(t)
+	tools, err = testQueries.Digests.NewTools(newcomer.ID, since, until, 1000)
+	require.NoError(t, err)
+	ids = digestToolIDs(tools)
+	require.Contains(t, ids, matching.ID)
+	require.Contains(t, ids, other.ID)
+
+	tools, err = testQueries.Digests.NewTools(newcomer.ID, until, until.Add(time.Minute), 1000)
+	require.NoError(t, err)
+	require.NotContains(t, digestToolIDs(tools), matching.ID)
+}
+
+func TestDigestModel_NewTools_ChosenCategories(t *testing.T) {
+	favorite := createPublishedTool(t, random.RandString(8))
+	user := CreateRandomUser(t)
+	err := testQueries.Favorites.AddFavorite(user.ID, favorite.ID)
+	require.NoError(t, err)
+
+	chosen := random.RandString(8)
+	dbUser, err := testQueries.Users.Get(user.ID, "")
+	require.NoError(t, err)
+	dbUser.DigestCategories = []string{chosen}
+	err = testQueries.Users.UpdatePreferences(dbUser)
+	require.NoError(t, err)
+
+	since := time.Now().Add(-time.Minute)
+	inChosen := createPublishedTool(t, chosen)
+	inFavorite := createPublishedTool(t, favorite.Category)
+	until := time.Now().Add(time.Minute)
+
+	// The chosen categories replace the ones inferred from favorites.
+	tools, err := testQueries.Digests.NewTools(user.ID, since, until, 1000)
+	require.NoError(t, err)
+	ids := digestToolIDs(tools)
+	require.Contains(t, ids, inChosen.ID)
+	require.NotContains(t, ids, inFavorite.ID)
+}
+
+func TestDigestModel_Due(t *testing.T) {
+	user := CreateRandomUser(t)
+	dbUser, err := testQueries.Users.Get(user.ID, "")
+	require.NoError(t, err)
+
+	dbUser.Digest = true
+	err = testQueries.Users.UpdatePreferences(dbUser)
+	require.NoError(t, err)
+
+	_, err = testQueries.Users.DB.Exec(`UPDATE users SET last_digest_at = NOW() - interval '8 days' WHERE id = $1`, user.ID)
+	require.NoError(t, err)
+
+	recipients, err := testQueries.Digests.GetDue(7*24*time.Hour, 100000)
+	require.NoError(t, err)
+
+	var recipient *DigestRecipient
+	for _, r := range recipients {
+		if r.UserID == user.ID {
+			recipient = r
+		}
+	}
+	require.NotNil(t, recipient)
+	require.Equal(t, user.Email, recipient.Email)
+
+	err = testQueries.Digests.MarkSent(recipient, time.Now())
+	require.NoError(t, err)
+	err = testQueries.Digests.MarkSent(recipient, time.Now())
+	require.ErrorIs(t, err, ErrEditConflict)
+
+	err = testQueries.Digests.Unsubscribe(user.ID)
+	require.NoError(t, err)
+	dbUser, err = testQueries.Users.Get(user.ID, "")
+	require.NoError(t, err)
+	require.False(t, dbUser.Digest)
+
+	err = testQueries.Digests.Unsubscribe(-1)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
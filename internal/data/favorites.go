@@ -41,9 +41,10 @@ func (m FavoriteModel) RemoveFavorite(userId, toolId int64) error {
 
 func (m FavoriteModel) GetFavorites(userId int64) ([]Favorite, error) {
 	query := `
-		SELECT user_id, tool_id
-		FROM favorites
-		WHERE user_id = $1
+		SELECT f.user_id, f.tool_id
+		FROM favorites f
+		INNER JOIN tools t ON t.id = f.tool_id
+		WHERE f.user_id = $1 AND t.deleted_at IS NULL
 		`
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -0,0 +1,58 @@
+package data
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+
+	validator "github.com/wdt/internal/validators"
+)
+
+// ImageVariant is one rendition of a tool's logo as produced by the upload
+// pipeline: the re-encoded original or a thumbnail, in PNG, JPEG or WebP.
+type ImageVariant struct {
+	Name   string `json:"name"`
+	Format string `json:"format"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	URL    string `json:"url"`
+}
+
+// ImageVariants is stored as a JSON array in the tools.images column.
+type ImageVariants []ImageVariant
+
+func (v ImageVariants) Value() (driver.Value, error) {
+	if v == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(v)
+}
+
+func (v *ImageVariants) Scan(src any) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return errors.New("images: expected jsonb")
+	}
+	*v = ImageVariants{}
+	return json.Unmarshal(b, v)
+}
+
+// Original returns the URL of the full size variant, or an empty string.
+func (v ImageVariants) Original() string {
+	for _, variant := range v {
+		if variant.Name == "original" {
+			return variant.URL
+		}
+	}
+	return ""
+}
+
+func ValidateImageVariants(v *validator.Validator, variants ImageVariants) {
+	v.Check(len(variants) <= 20, "images", "must not have more than 20 variants")
+	for _, variant := range variants {
+		v.Check(variant.Name != "", "images", "every variant must have a name")
+		v.Check(validator.PermittedValue(variant.Format, "png", "jpeg", "webp"), "images", "format must be png, jpeg or webp")
+		v.Check(variant.URL != "", "images", "every variant must have a url")
+		v.Check(len(variant.URL) <= 500, "images", "url must not be more than 500 bytes long")
+	}
+}
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 )
@@ -10,21 +11,47 @@ var (
 	ErrEditConflict   = errors.New("edit conflict")
 )
 
+// querier is satisfied by both *sql.DB and *sql.Tx so helpers can run inside
+// or outside of a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 type Models struct {
-	Users      UserModel
-	Tokens     TokenModel
-	Tools      ToolModel
-	Categories CategoryModel
-	Favorites  FavoriteModel
+	Users           UserModel
+	Tokens          TokenModel
+	Tools           ToolModel
+	Categories      CategoryModel
+	Favorites       FavoriteModel
+	Revisions       ToolRevisionModel
+	Alternatives    AlternativeModel
+	Recommendations RecommendationModel
+	Analytics       AnalyticsModel
+	WebsiteChecks   WebsiteCheckModel
+	Uploads         UploadModel
+	Outbox          OutboxModel
+	Digests         DigestModel
+	Suppressions    SuppressionModel
 }
 
 func NewModels(db *sql.DB) Models {
 	return Models{
-		Users:      UserModel{DB: db},
-		Tokens:     TokenModel{DB: db},
-		Tools:      ToolModel{DB: db},
-		Categories: CategoryModel{DB: db},
-		Favorites:  FavoriteModel{DB: db},
+		Users:           UserModel{DB: db},
+		Tokens:          TokenModel{DB: db},
+		Tools:           ToolModel{DB: db},
+		Categories:      CategoryModel{DB: db},
+		Favorites:       FavoriteModel{DB: db},
+		Revisions:       ToolRevisionModel{DB: db},
+		Alternatives:    AlternativeModel{DB: db},
+		Recommendations: RecommendationModel{DB: db},
+		Analytics:       AnalyticsModel{DB: db},
+		WebsiteChecks:   WebsiteCheckModel{DB: db},
+		Uploads:         UploadModel{DB: db},
+		Outbox:          OutboxModel{DB: db},
+		Digests:         DigestModel{DB: db},
+		Suppressions:    SuppressionModel{DB: db},
 	}
 }
 
@@ -37,3 +64,13 @@ func NewNullString(s string) sql.NullString {
 		Valid:  true,
 	}
 }
+
+func newNullInt64(i int64) sql.NullInt64 {
+	if i == 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{
+		Int64: i,
+		Valid: true,
+	}
+}
@@ -0,0 +1,375 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	EmailPending    = "pending"
+	EmailSending    = "sending"
+	EmailSent       = "sent"
+	EmailDead       = "dead"
+	EmailSuppressed = "suppressed"
+)
+
+// OutboxEmail is an email waiting in the outbox to be sent by the outbox
+// workers. IdempotencyKey, when set, makes enqueueing the same email twice a
+// no-op. An email is dead once it has failed too often to be retried and
+// suppressed when its recipient must not receive email.
+type OutboxEmail struct {
+	ID             int64        `json:"id"`
+	IdempotencyKey string       `json:"idempotencyKey,omitempty"`
+	From           string       `json:"from"`
+	To             string       `json:"to"`
+	Subject        string       `json:"subject"`
+	HTML           string       `json:"-"`
+	Text           string       `json:"-"`
+	Headers        EmailHeaders `json:"headers,omitempty"`
+	Status         string       `json:"status"`
+	Attempts       int          `json:"attempts"`
+	LastError      string       `json:"lastError,omitempty"`
+	NextAttemptAt  time.Time    `json:"nextAttemptAt"`
+	CreatedAt      time.Time    `json:"createdAt"`
+	SentAt         *time.Time   `json:"sentAt,omitempty"`
+}
+
+// EmailHeaders holds the extra headers of an email, stored as a jsonb
+// object.
+type EmailHeaders map[string]string
+
+func (h EmailHeaders) Value() (driver.Value, error) {
+	if h == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(h)
+}
+
+func (h *EmailHeaders) Scan(src any) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("email headers: unexpected type %T", src)
+	}
+	return json.Unmarshal(b, h)
+}
+
+// OutboxBackoff returns how long to wait before the next attempt after an
+// email failed for the given number of times: 30 seconds, doubling with
+// every attempt up to an hour.
+func OutboxBackoff(attempts int) time.Duration {
+	delay := 30 * time.Second
+	for i := 1; i < attempts && delay < time.Hour; i++ {
+		delay *= 2
+	}
+	return min(delay, time.Hour)
+}
+
+// IdempotencyWindow is how long an idempotency key keeps an email from
+// being queued again. A digest or notice that legitimately repeats the key
+// later, such as next week's digest, is queued as a new email.
+const IdempotencyWindow = 24 * time.Hour
+
+type OutboxModel struct {
+	DB *sql.DB
+}
+
+// Enqueue adds email to the outbox. When an email with the same idempotency
+// key was queued within IdempotencyWindow nothing is added and email is
+// filled in from the existing one instead. Older emails give up their key.
+func (m OutboxModel) Enqueue(email *OutboxEmail) error {
+	expire := `UPDATE email_outbox SET idempotency_key = NULL
+			   WHERE idempotency_key = $1 AND created_at < NOW() - make_interval(secs => $2)`
+
+	query := `INSERT INTO email_outbox (idempotency_key, sender, recipient, subject, html, text, headers)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7)
+			  ON CONFLICT (idempotency_key) DO UPDATE SET idempotency_key = EXCLUDED.idempotency_key
+			  RETURNING id, status, attempts, next_attempt_at, created_at, sent_at`
+
+	args := []interface{}{
+		NewNullString(email.IdempotencyKey),
+		email.From,
+		email.To,
+		email.Subject,
+		email.HTML,
+		email.Text,
+		email.Headers,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if email.IdempotencyKey != "" {
+		_, err = tx.ExecContext(ctx, expire, email.IdempotencyKey, IdempotencyWindow.Seconds())
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(
+		&email.ID,
+		&email.Status,
+		&email.Attempts,
+		&email.NextAttemptAt,
+		&email.CreatedAt,
+		&email.SentAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Claim locks up to limit emails that are due for sending and counts the
+// attempt. The lock expires after lease so emails claimed by a worker that
+// died are picked up again.
+func (m OutboxModel) Claim(limit int, lease time.Duration) ([]*OutboxEmail, error) {
+	query := `UPDATE email_outbox
+			  SET status = 'sending', attempts = attempts + 1, locked_until = NOW() + make_interval(secs => $2)
+			  WHERE id IN (
+			      SELECT id FROM email_outbox
+			      WHERE (status = 'pending' AND next_attempt_at <= NOW())
+			         OR (status = 'sending' AND locked_until < NOW())
+			      ORDER BY next_attempt_at
+			      LIMIT $1
+			      FOR UPDATE SKIP LOCKED
+			  )
+			  RETURNING ` + outboxColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var emails []*OutboxEmail
+	for rows.Next() {
+		email, err := scanOutboxEmail(rows)
+		if err != nil {
+			return nil, err
+		}
+		emails = append(emails, email)
+	}
+
+	return emails, rows.Err()
+}
+
+// MarkSent records that a claimed email was delivered to the mail backend.
+func (m OutboxModel) MarkSent(email *OutboxEmail) error {
+	query := `UPDATE email_outbox
+			  SET status = 'sent', last_error = '', locked_until = NULL, sent_at = NOW()
+			  WHERE id = $1 AND status = 'sending'
+			  RETURNING status, sent_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, email.ID).Scan(&email.Status, &email.SentAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MarkFailed records a failed attempt of a claimed email. It is retried
+// after OutboxBackoff unless it has used up maxAttempts, in which case it
+// is dead.
+func (m OutboxModel) MarkFailed(email *OutboxEmail, sendErr error, maxAttempts int) error {
+	status := EmailPending
+	if email.Attempts >= maxAttempts {
+		status = EmailDead
+	}
+
+	query := `UPDATE email_outbox
+			  SET status = $1, last_error = $2, locked_until = NULL, next_attempt_at = NOW() + make_interval(secs => $3)
+			  WHERE id = $4 AND status = 'sending'
+			  RETURNING status, last_error, next_attempt_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, status, sendErr.Error(), OutboxBackoff(email.Attempts).Seconds(), email.ID).Scan(
+		&email.Status,
+		&email.LastError,
+		&email.NextAttemptAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MarkSuppressed records that a claimed email was not sent because its
+// recipient is suppressed. It is not retried.
+func (m OutboxModel) MarkSuppressed(email *OutboxEmail, reason error) error {
+	query := `UPDATE email_outbox
+			  SET status = 'suppressed', last_error = $1, locked_until = NULL
+			  WHERE id = $2 AND status = 'sending'
+			  RETURNING status, last_error`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, reason.Error(), email.ID).Scan(&email.Status, &email.LastError)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Retry puts a dead email back in the queue with a fresh set of attempts.
+func (m OutboxModel) Retry(id int64) (*OutboxEmail, error) {
+	query := `UPDATE email_outbox
+			  SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+			  WHERE id = $1 AND status = 'dead'
+			  RETURNING ` + outboxColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	email, err := scanOutboxEmail(m.DB.QueryRowContext(ctx, query, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return email, nil
+}
+
+// GetAll lists outbox emails for admins, newest first. A non-empty status
+// limits the list to emails in that state.
+func (m OutboxModel) GetAll(status string, filters Filters) ([]*OutboxEmail, Metadata, error) {
+	query := `SELECT count(*) OVER(), ` + outboxColumns + `
+			  FROM email_outbox
+			  WHERE ($1 = '' OR status = $1)
+			  ORDER BY created_at DESC, id DESC
+			  LIMIT $2 OFFSET $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, status, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	emails := []*OutboxEmail{}
+
+	for rows.Next() {
+		var email *OutboxEmail
+		email, err = scanOutboxEmail(&countingScanner{rows: rows, count: &totalRecords})
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		emails = append(emails, email)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return emails, metadata, nil
+}
+
+// Stats counts the outbox emails in each state.
+func (m OutboxModel) Stats() (map[string]int, error) {
+	query := `SELECT status, count(*) FROM email_outbox GROUP BY status`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := map[string]int{EmailPending: 0, EmailSending: 0, EmailSent: 0, EmailDead: 0, EmailSuppressed: 0}
+	for rows.Next() {
+		var status string
+		var count int
+		err = rows.Scan(&status, &count)
+		if err != nil {
+			return nil, err
+		}
+		stats[status] = count
+	}
+
+	return stats, rows.Err()
+}
+
+const outboxColumns = `id, coalesce(idempotency_key, ''), sender, recipient, subject, html, text, headers, status, attempts, last_error, next_attempt_at, created_at, sent_at`
+
+func scanOutboxEmail(row interface{ Scan(dest ...any) error }) (*OutboxEmail, error) {
+	var email OutboxEmail
+	err := row.Scan(
+		&email.ID,
+		&email.IdempotencyKey,
+		&email.From,
+		&email.To,
+		&email.Subject,
+		&email.HTML,
+		&email.Text,
+		&email.Headers,
+		&email.Status,
+		&email.Attempts,
+		&email.LastError,
+		&email.NextAttemptAt,
+		&email.CreatedAt,
+		&email.SentAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &email, nil
+}
+
+// countingScanner reads the leading count(*) OVER() column of a row before
+// handing the rest to a scan helper.
+type countingScanner struct {
+	rows  *sql.Rows
+	count *int
+}
+
+func (s *countingScanner) Scan(dest ...any) error {
+	return s.rows.Scan(append([]any{s.count}, dest...)...)
+}
@@ -0,0 +1,139 @@
+package data
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"github.com/wdt/internal/random"
+	"testing"
+	"time"
+)
+
+func CreateOutboxEmail(t *testing.T, idempotencyKey string) *OutboxEmail {
+	email := &OutboxEmail{
+		IdempotencyKey: idempotencyKey,
+		From:           "Web Dev Tools <info@web-dev-tools.xyz>",
+		To:             random.RandString(10) + "@gmail.com",
+		Subject:        "Hello",
+		HTML:           "<p>Hello</p>",
+		Headers:        EmailHeaders{"List-Unsubscribe": "<https://example.com/unsubscribe>"},
+	}
+
+	err := testQueries.Outbox.Enqueue(email)
+	require.NoError(t, err)
+	require.NotZero(t, email.ID)
+	require.Equal(t, EmailPending, email.Status)
+
+	return email
+}
+
+// claimOutboxEmail claims due emails and returns the given one among them.
+func claimOutboxEmail(t *testing.T, id int64) *OutboxEmail {
+	emails, err := testQueries.Outbox.Claim(1000, time.Minute)
+	require.NoError(t, err)
+
+	for _, email := range emails {
+		if email.ID == id {
+			return email
+		}
+	}
+	require.FailNow(t, "email was not claimed")
+	return nil
+}
+
+func TestOutboxModel_Enqueue_Idempotent(t *testing.T) {
+	key := "test:" + random.RandString(10)
+	email := CreateOutboxEmail(t, key)
+
+	again := &OutboxEmail{IdempotencyKey: key, From: email.From, To: "other@gmail.com", Subject: "Other"}
+	err := testQueries.Outbox.Enqueue(again)
+	require.NoError(t, err)
+	require.Equal(t, email.ID, again.ID)
+
+	without := CreateOutboxEmail(t, "")
+	require.NotEqual(t, email.ID, without.ID)
+}
+
+func TestOutboxModel_Enqueue_IdempotencyWindow(t *testing.T) {
+	key := "test:" + random.RandString(10)
+	email := CreateOutboxEmail(t, key)
+
+	_, err := testQueries.Outbox.DB.Exec(`UPDATE email_outbox SET created_at = NOW() - interval '25 hours' WHERE id = $1`, email.ID)
+	require.NoError(t, err)
+
+	again := CreateOutboxEmail(t, key)
+	require.NotEqual(t, email.ID, again.ID)
+
+	third := &OutboxEmail{IdempotencyKey: key, From: email.From, To: "other@gmail.com", Subject: "Other"}
+	err = testQueries.Outbox.Enqueue(third)
+	require.NoError(t, err)
+	require.Equal(t, again.ID, third.ID)
+}
+
+func TestOutboxModel_Send(t *testing.T) {
+	email := CreateOutboxEmail(t, "")
+
+	claimed := claimOutboxEmail(t, email.ID)
+	require.Equal(t, EmailSending, claimed.Status)
+	require.Equal(t, 1, claimed.Attempts)
+	require.Equal(t, email.Headers, claimed.Headers)
+
+	err := testQueries.Outbox.MarkSent(claimed)
+	require.NoError(t, err)
+	require.Equal(t, EmailSent, claimed.Status)
+	require.NotNil(t, claimed.SentAt)
+
+	err = testQueries.Outbox.MarkSent(claimed)
+	require.ErrorIs(t, err, ErrEditConflict)
+}
+
+func TestOutboxModel_DeadLetter(t *testing.T) {
+	email := CreateOutboxEmail(t, "")
+
+	claimed := claimOutboxEmail(t, email.ID)
+	err := testQueries.Outbox.MarkFailed(claimed, errors.New("connection refused"), 2)
+	require.NoError(t, err)
+	require.Equal(t, EmailPending, claimed.Status)
+	require.Equal(t, "connection refused", claimed.LastError)
+	require.True(t, claimed.NextAttemptAt.After(time.Now()))
+
+	_, err = testQueries.Outbox.Retry(email.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// Simulate the second attempt without waiting for the backoff.
+	claimed.Attempts = 2
+	_, err = testQueries.Outbox.DB.Exec(`UPDATE email_outbox SET status = 'sending', attempts = 2 WHERE id = $1`, email.ID)
+	require.NoError(t, err)
+
+	err = testQueries.Outbox.MarkFailed(claimed, errors.New("connection refused"), 2)
+	require.NoError(t, err)
+	require.Equal(t, EmailDead, claimed.Status)
+
+	dead, _, err := testQueries.Outbox.GetAll(EmailDead, Filters{Page: 1, PageSize: 100})
+	require.NoError(t, err)
+	require.Contains(t, outboxIDs(dead), email.ID)
+
+	retried, err := testQueries.Outbox.Retry(email.ID)
+	require.NoError(t, err)
+	require.Equal(t, EmailPending, retried.Status)
+	require.Zero(t, retried.Attempts)
+
+	stats, err := testQueries.Outbox.Stats()
+	require.NoError(t, err)
+	require.Positive(t, stats[EmailPending])
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	require.Equal(t, 30*time.Second, OutboxBackoff(1))
+	require.Equal(t, time.Minute, OutboxBackoff(2))
+	require.Equal(t, 4*time.Minute, OutboxBackoff(4))
+	require.Equal(t, time.Hour, OutboxBackoff(8))
+	require.Equal(t, time.Hour, OutboxBackoff(100))
+}
+
+func outboxIDs(emails []*OutboxEmail) []int64 {
+	ids := make([]int64, len(emails))
+	for i, email := range emails {
+		ids[i] = email.ID
+	}
+	return ids
+}
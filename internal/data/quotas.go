@@ -0,0 +1,160 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrQuotaExceeded = errors.New("upload quota exceeded")
+
+// UploadQuota limits how much a user may upload per UTC day. A zero limit
+// means unlimited.
+type UploadQuota struct {
+	DailyBytes int64 `json:"dailyBytes"`
+	DailyFiles int64 `json:"dailyFiles"`
+}
+
+// UploadUsage is what a user uploaded since the start of the day. Pending
+// and rejected uploads count too, so failed attempts cannot be repeated
+// for free.
+type UploadUsage struct {
+	Bytes int64 `json:"usedBytes"`
+	Files int64 `json:"usedFiles"`
+}
+
+// Allows reports whether another upload of size bytes fits the quota.
+func (q UploadQuota) Allows(usage UploadUsage, size int64) bool {
+	if q.DailyBytes > 0 && usage.Bytes+size > q.DailyBytes {
+		return false
+	}
+	if q.DailyFiles > 0 && usage.Files+1 > q.DailyFiles {
+		return false
+	}
+	return true
+}
+
+// QuotaStatus is reported by GET /v1/users/quota. Remaining values are nil
+// for unlimited quotas.
+type QuotaStatus struct {
+	UploadQuota
+	UploadUsage
+	RemainingBytes *int64    `json:"remainingBytes"`
+	RemainingFiles *int64    `json:"remainingFiles"`
+	ResetsAt       time.Time `json:"resetsAt"`
+}
+
+func NewQuotaStatus(quota UploadQuota, usage UploadUsage, resetsAt time.Time) QuotaStatus {
+	remaining := func(limit, used int64) *int64 {
+		if limit == 0 {
+			return nil
+		}
+		r := max(limit-used, 0)
+		return &r
+	}
+
+	return QuotaStatus{
+		UploadQuota:    quota,
+		UploadUsage:    usage,
+		RemainingBytes: remaining(quota.DailyBytes, usage.Bytes),
+		RemainingFiles: remaining(quota.DailyFiles, usage.Files),
+		ResetsAt:       resetsAt,
+	}
+}
+
+// QuotaDay returns the start of the current quota day and when it ends.
+func QuotaDay(now time.Time) (time.Time, time.Time) {
+	start := now.UTC().Truncate(24 * time.Hour)
+	return start, start.Add(24 * time.Hour)
+}
+
+// ParseRoleQuotas parses per-role overrides written as
+// "role=bytes/files,role=bytes/files", e.g. "admin=0/0" to lift every limit
+// for admins.
+func ParseRoleQuotas(s string) (map[string]UploadQuota, error) {
+	quotas := map[string]UploadQuota{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		role, limits, ok := strings.Cut(entry, "=")
+		bytes, files, ok2 := strings.Cut(limits, "/")
+		if !ok || !ok2 || strings.TrimSpace(role) == "" {
+			return nil, fmt.Errorf("invalid role quota %q, expected role=bytes/files", entry)
+		}
+
+		var quota UploadQuota
+		var err error
+		quota.DailyBytes, err = strconv.ParseInt(strings.TrimSpace(bytes), 10, 64)
+		if err != nil || quota.DailyBytes < 0 {
+			return nil, fmt.Errorf("invalid byte limit in role quota %q", entry)
+		}
+		quota.DailyFiles, err = strconv.ParseInt(strings.TrimSpace(files), 10, 64)
+		if err != nil || quota.DailyFiles < 0 {
+			return nil, fmt.Errorf("invalid file limit in role quota %q", entry)
+		}
+
+		quotas[strings.TrimSpace(role)] = quota
+	}
+	return quotas, nil
+}
+
+// UsageSince sums the uploads userID started since the given time. Bytes
+// counts what each upload takes up in storage: its size until it completes,
+// then its original plus every variant.
+func (m UploadModel) UsageSince(userID int64, since time.Time) (UploadUsage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return uploadUsageSince(ctx, m.DB, userID, since)
+}
+
+func uploadUsageSince(ctx context.Context, q querier, userID int64, since time.Time) (UploadUsage, error) {
+	query := `SELECT coalesce(sum(greatest(size, stored_bytes)), 0), count(*)
+			  FROM uploads
+			  WHERE user_id = $1 AND created_at >= $2`
+
+	var usage UploadUsage
+	err := q.QueryRowContext(ctx, query, userID, since).Scan(&usage.Bytes, &usage.Files)
+	return usage, err
+}
+
+// InsertWithinQuota inserts upload unless it would take its owner over
+// quota for the day starting at since, in which case it returns
+// ErrQuotaExceeded. Concurrent uploads of the same user are serialized so
+// they cannot both squeeze under the limit.
+func (m UploadModel) InsertWithinQuota(upload *Upload, quota UploadQuota, since time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, upload.UserID)
+	if err != nil {
+		return err
+	}
+
+	usage, err := uploadUsageSince(ctx, tx, upload.UserID, since)
+	if err != nil {
+		return err
+	}
+	if !quota.Allows(usage, upload.Size) {
+		return ErrQuotaExceeded
+	}
+
+	err = insertUpload(ctx, tx, upload)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,84 @@
+package data
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseRoleQuotas(t *testing.T) {
+	quotas, err := ParseRoleQuotas(" admin=0/0, trusted=1048576/200 ,")
+	require.NoError(t, err)
+	require.Equal(t, map[string]UploadQuota{
+		"admin":   {},
+		"trusted": {DailyBytes: 1048576, DailyFiles: 200},
+	}, quotas)
+
+	quotas, err = ParseRoleQuotas("")
+	require.NoError(t, err)
+	require.Empty(t, quotas)
+
+	for _, s := range []string{"admin", "admin=1", "=1/1", "admin=-1/1", "admin=1/x"} {
+		_, err := ParseRoleQuotas(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestUploadQuota_Allows(t *testing.T) {
+	quota := UploadQuota{DailyBytes: 100, DailyFiles: 2}
+
+	require.True(t, quota.Allows(UploadUsage{Bytes: 50, Files: 1}, 50))
+	require.False(t, quota.Allows(UploadUsage{Bytes: 50, Files: 1}, 51))
+	require.False(t, quota.Allows(UploadUsage{Bytes: 0, Files: 2}, 1))
+	require.True(t, UploadQuota{}.Allows(UploadUsage{Bytes: 1 << 40, Files: 1 << 20}, 1<<30))
+
+	status := NewQuotaStatus(UploadQuota{DailyBytes: 100}, UploadUsage{Bytes: 150, Files: 3}, time.Time{})
+	require.Equal(t, int64(0), *status.RemainingBytes)
+	require.Nil(t, status.RemainingFiles)
+}
+
+func TestUploadModel_InsertWithinQuota(t *testing.T) {
+	user := CreateRandomUser(t)
+	since, _ := QuotaDay(time.Now())
+	quota := UploadQuota{DailyBytes: 3000, DailyFiles: 2}
+
+	newUpload := func(size int64) *Upload {
+		return &Upload{
+			ID:          uuid.NewString(),
+			UserID:      user.ID,
+			ContentType: "image/png",
+			Size:        size,
+			Status:      UploadPending,
+			ExpiresAt:   time.Now().Add(time.Minute),
+		}
+	}
+
+	err := testQueries.Uploads.InsertWithinQuota(newUpload(2000), quota, since)
+	require.NoError(t, err)
+
+	err = testQueries.Uploads.InsertWithinQuota(newUpload(1001), quota, since)
+	require.ErrorIs(t, err, ErrQuotaExceeded)
+
+	err = testQueries.Uploads.InsertWithinQuota(newUpload(1000), quota, since)
+	require.NoError(t, err)
+
+	err = testQueries.Uploads.InsertWithinQuota(newUpload(1), quota, since)
+	require.ErrorIs(t, err, ErrQuotaExceeded)
+
+	usage, err := testQueries.Uploads.UsageSince(user.ID, since)
+	require.NoError(t, err)
+	require.Equal(t, UploadUsage{Bytes: 3000, Files: 2}, usage)
+
+	// Completed uploads count their stored variants.
+	upload := newUpload(100)
+	err = testQueries.Uploads.InsertWithinQuota(upload, UploadQuota{}, since)
+	require.NoError(t, err)
+	upload.StoredBytes = 500
+	err = testQueries.Uploads.Complete(upload)
+	require.NoError(t, err)
+
+	usage, err = testQueries.Uploads.UsageSince(user.ID, since)
+	require.NoError(t, err)
+	require.Equal(t, UploadUsage{Bytes: 3500, Files: 3}, usage)
+}
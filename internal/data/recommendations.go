@@ -0,0 +1,191 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	RecommendationSimilar = "similar"
+	RecommendationPopular = "popular"
+)
+
+// Recommendation is a published tool suggested to a user. Reason tells
+// whether it came from users with similar favorites or from the most
+// popular tools of a category.
+type Recommendation struct {
+	*Tool
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason"`
+}
+
+type RecommendationModel struct {
+	DB *sql.DB
+}
+
+// Refresh recomputes the stored recommendations in one transaction, so
+// readers never see a half-built set. It first ranks the perCategory most
+// favorited tools of every category, then gives each user with favorites up
+// to perUser tools they have not favorited yet. Tools are scored by item-item
+// collaborative filtering: the cosine similarity of their fan sets, summed
+// over the user's favorites. Users whose favorites share no fans with other
+// tools are topped up with popular tools from their favorite categories.
+func (m RecommendationModel) Refresh(perUser, perCategory int) (int64, error) {
+	popularQuery := `INSERT INTO popular_tools (tool_id, category, category_rank, score)
+					 SELECT tool_id, category, category_rank, fans
+					 FROM (
+						 SELECT t.id AS tool_id, t.category, count(DISTINCT f.user_id) AS fans,
+								row_number() OVER (PARTITION BY t.category ORDER BY count(DISTINCT f.user_id) DESC, t.created_at DESC, t.id) AS category_rank
+						 FROM tools t
+						 LEFT JOIN favorites f ON f.tool_id = t.id
+						 WHERE t.published = true AND t.deleted_at IS NULL
+						 GROUP BY t.id
+					 ) p
+					 WHERE category_rank <= $1`
+
+	userQuery := `WITH fav AS (
+					  SELECT DISTINCT f.user_id, f.tool_id, t.category
+					  FROM favorites f
+					  INNER JOIN tools t ON t.id = f.tool_id
+					  WHERE t.published = true AND t.deleted_at IS NULL
+				  ),
+				  popularity AS (
+					  SELECT tool_id, count(*) AS fans FROM fav GROUP BY tool_id
+				  ),
+				  similarity AS (
+					  SELECT a.tool_id AS item, b.tool_id AS other, count(*) / sqrt(pa.fans * pb.fans) AS score
+					  FROM fav a
+					  INNER JOIN fav b ON b.user_id = a.user_id AND b.tool_id <> a.tool_id
+					  INNER JOIN popularity pa ON pa.tool_id = a.tool_id
+					  INNER JOIN popularity pb ON pb.tool_id = b.tool_id
+					  GROUP BY a.tool_id, b.tool_id, pa.fans, pb.fans
+				  ),
+				  cf AS (
+					  SELECT f.user_id, s.other AS tool_id, sum(s.score) AS score, 0 AS priority
+					  FROM fav f
+					  INNER JOIN similarity s ON s.item = f.tool_id
+					  GROUP BY f.user_id, s.other
+				  ),
+				  fallback AS (
+					  SELECT DISTINCT f.user_id, p.tool_id, p.score, 1 AS priority
+					  FROM fav f
+					  INNER JOIN popular_tools p ON p.category = f.category
+				  ),
+				  candidates AS (
+					  SELECT DISTINCT ON (c.user_id, c.tool_id) c.user_id, c.tool_id, c.score, c.priority
+					  FROM (SELECT * FROM cf UNION ALL SELECT * FROM fallback) c
+					  WHERE NOT EXISTS (SELECT 1 FROM fav seen WHERE seen.user_id = c.user_id AND seen.tool_id = c.tool_id)
+					  ORDER BY c.user_id, c.tool_id, c.priority
+				  ),
+				  ranked AS (
+					  SELECT user_id, tool_id, score, priority,
+							 row_number() OVER (PARTITION BY user_id ORDER BY priority, score DESC, tool_id) AS rank
+					  FROM candidates
+				  )
+				  INSERT INTO user_recommendations (user_id, tool_id, rank, score, reason)
+				  SELECT user_id, tool_id, rank, score, CASE WHEN priority = 0 THEN $2 ELSE $3 END
+				  FROM ranked
+				  WHERE rank <= $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM popular_tools`)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, popularQuery, perCategory)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM user_recommendations`)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.ExecContext(ctx, userQuery, perUser, RecommendationSimilar, RecommendationPopular)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return rows, tx.Commit()
+}
+
+// GetForUser reads the precomputed recommendations of a user. Users the last
+// refresh knew nothing about get the most popular tools of every category
+// instead, leaving out anything they already favorited.
+func (m RecommendationModel) GetForUser(userID int64, limit int) ([]*Recommendation, error) {
+	query := `SELECT t.id, t.created_at, t.name, t.slug, t.category, t.images, t.description, t.website, r.score, r.reason
+			  FROM user_recommendations r
+			  INNER JOIN tools t ON t.id = r.tool_id
+			  WHERE r.user_id = $1 AND t.published = true AND t.deleted_at IS NULL
+			  ORDER BY r.rank
+			  LIMIT $2`
+
+	recommendations, err := m.query(query, userID, limit)
+	if err != nil || len(recommendations) > 0 {
+		return recommendations, err
+	}
+
+	query = `SELECT t.id, t.created_at, t.name, t.slug, t.category, t.images, t.description, t.website, p.score, $3
+			 FROM popular_tools p
+			 INNER JOIN tools t ON t.id = p.tool_id
+			 WHERE t.published = true AND t.deleted_at IS NULL
+			 AND NOT EXISTS (SELECT 1 FROM favorites f WHERE f.user_id = $1 AND f.tool_id = p.tool_id)
+			 ORDER BY p.category_rank, p.score DESC, p.tool_id
+			 LIMIT $2`
+
+	return m.query(query, userID, limit, RecommendationPopular)
+}
+
+func (m RecommendationModel) query(query string, args ...any) ([]*Recommendation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recommendations := []*Recommendation{}
+	for rows.Next() {
+		recommendation := Recommendation{Tool: &Tool{}}
+		err := rows.Scan(
+			&recommendation.ID,
+			&recommendation.CreatedAt,
+			&recommendation.Name,
+			&recommendation.Slug,
+			&recommendation.Category,
+			&recommendation.Images,
+			&recommendation.Description,
+			&recommendation.Website,
+			&recommendation.Score,
+			&recommendation.Reason,
+		)
+		if err != nil {
+			return nil, err
+		}
+		recommendations = append(recommendations, &recommendation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return recommendations, nil
+}
@@ -0,0 +1,42 @@
+package data
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRecommendationModel_Refresh(t *testing.T) {
+	var tools []Tool
+	for i := 0; i < 2; i++ {
+		tool := CreateTool(t)
+		tool.Published = true
+		err := testQueries.Tools.Update(&tool, 0)
+		require.NoError(t, err)
+		tools = append(tools, tool)
+	}
+
+	fan := CreateRandomUser(t)
+	newcomer := CreateRandomUser(t)
+	coldStart := CreateRandomUser(t)
+
+	require.NoError(t, testQueries.Favorites.AddFavorite(fan.ID, tools[0].ID))
+	require.NoError(t, testQueries.Favorites.AddFavorite(fan.ID, tools[1].ID))
+	require.NoError(t, testQueries.Favorites.AddFavorite(newcomer.ID, tools[0].ID))
+
+	_, err := testQueries.Recommendations.Refresh(50, 10)
+	require.NoError(t, err)
+
+	recommendations, err := testQueries.Recommendations.GetForUser(newcomer.ID, 10)
+	require.NoError(t, err)
+	require.NotEmpty(t, recommendations)
+	require.Equal(t, tools[1].ID, recommendations[0].ID)
+	require.Equal(t, RecommendationSimilar, recommendations[0].Reason)
+	for _, recommendation := range recommendations {
+		require.NotEqual(t, tools[0].ID, recommendation.ID)
+	}
+
+	recommendations, err = testQueries.Recommendations.GetForUser(coldStart.ID, 10)
+	require.NoError(t, err)
+	require.NotEmpty(t, recommendations)
+	require.Equal(t, RecommendationPopular, recommendations[0].Reason)
+}
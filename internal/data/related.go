@@ -0,0 +1,231 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var ErrSelfAlternative = errors.New("tool cannot be its own alternative")
+
+// RelatedWeights blends the signals that make two tools related. Each signal
+// is scored between 0 and 1 before it is multiplied by its weight.
+type RelatedWeights struct {
+	Category  float64
+	Favorites float64
+	Text      float64
+}
+
+// RelatedTool is a published tool together with how closely it relates to the
+// tool it was looked up for. Pinned tools were linked by a curator.
+type RelatedTool struct {
+	*Tool
+	Score  float64 `json:"score"`
+	Pinned bool    `json:"pinned"`
+}
+
+// GetRelated ranks published tools by how related they are to the tool with
+// the given id. Pinned alternatives always come first; the rest are scored on
+// a shared category (half a point for a parent, child or sibling category),
+// the share of the tool's fans who also favorited them and full-text
+// similarity of name and description. Tools scoring zero are left out.
+func (m ToolModel) GetRelated(id int64, weights RelatedWeights, limit int) ([]*RelatedTool, error) {
+	query := `WITH src AS (
+				  SELECT t.id, t.category, c.id AS category_id, c.parent_id AS category_parent,
+						 to_tsvector('simple', t.name || ' ' || t.description) AS doc
+				  FROM tools t
+				  LEFT JOIN categories c ON c.name = t.category AND c.deleted_at IS NULL
+				  WHERE t.id = $1
+			  ),
+			  -- The lexemes are already normalized, so they are quoted for the
+			  -- tsquery input syntax (doubling quotes and backslashes) and cast
+			  -- instead of being parsed again by to_tsquery.
+			  terms AS (
+				  SELECT string_agg('''' || replace(replace(lexeme, '\', '\\'), '''', '''''') || '''', ' | ')::tsquery AS query
+				  FROM src, unnest(tsvector_to_array(src.doc)) AS lexeme
+			  ),
+			  fans AS (
+				  SELECT DISTINCT user_id FROM favorites WHERE tool_id = $1
+			  ),
+			  co AS (
+				  SELECT f.tool_id, count(DISTINCT f.user_id)::float8 / (SELECT greatest(count(*), 1) FROM fans) AS score
+				  FROM favorites f
+				  WHERE f.user_id IN (SELECT user_id FROM fans) AND f.tool_id <> $1
+				  GROUP BY f.tool_id
+			  ),
+			  pinned AS (
+				  SELECT CASE WHEN tool_id = $1 THEN alternative_id ELSE tool_id END AS tool_id
+				  FROM tool_alternatives
+				  WHERE tool_id = $1 OR alternative_id = $1
+			  ),
+			  scored AS (
+				  SELECT t.id, t.created_at, t.name, t.slug, t.category, t.images, t.description, t.website,
+						 p.tool_id IS NOT NULL AS pinned,
+						 $2::float8 * (CASE
+							 WHEN t.category = src.category THEN 1
+							 WHEN c.parent_id = src.category_id OR c.id = src.category_parent OR c.parent_id = src.category_parent THEN 0.5
+							 ELSE 0
+						 END)::float8
+						 + $3::float8 * coalesce(co.score, 0)
+						 + $4::float8 * coalesce(ts_rank(to_tsvector('simple', t.name || ' ' || t.description), terms.query, 32), 0) AS score
+				  FROM tools t
+				  CROSS JOIN src
+				  CROSS JOIN terms
+				  LEFT JOIN categories c ON c.name = t.category AND c.deleted_at IS NULL
+				  LEFT JOIN co ON co.tool_id = t.id
+				  LEFT JOIN pinned p ON p.tool_id = t.id
+				  WHERE t.id <> $1 AND t.published = true AND t.deleted_at IS NULL
+			  )
+			  SELECT id, created_at, name, slug, category, images, description, website, pinned, score
+			  FROM scored
+			  WHERE pinned OR score > 0
+			  ORDER BY pinned DESC, score DESC, id
+			  LIMIT $5`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, id, weights.Category, weights.Favorites, weights.Text, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	related := []*RelatedTool{}
+	for rows.Next() {
+		item := RelatedTool{Tool: &Tool{}}
+		err := rows.Scan(
+			&item.ID,
+			&item.CreatedAt,
+			&item.Name,
+			&item.Slug,
+			&item.Category,
+			&item.Images,
+			&item.Description,
+			&item.Website,
+			&item.Pinned,
+			&item.Score,
+		)
+		if err != nil {
+			return nil, err
+		}
+		related = append(related, &item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return related, nil
+}
+
+// AlternativeModel stores the curated "alternative to" links between tools.
+// Links are symmetric and kept once per pair, with the lower id first.
+type AlternativeModel struct {
+	DB *sql.DB
+}
+
+func alternativePair(toolID, alternativeID int64) (int64, int64) {
+	if toolID > alternativeID {
+		return alternativeID, toolID
+	}
+	return toolID, alternativeID
+}
+
+// Add links two tools as alternatives of each other. Linking an existing pair
+// again is a no-op.
+func (m AlternativeModel) Add(toolID, alternativeID int64) error {
+	if toolID == alternativeID {
+		return ErrSelfAlternative
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var found int
+	err := m.DB.QueryRowContext(ctx, `SELECT count(*) FROM tools WHERE id IN ($1, $2) AND deleted_at IS NULL`, toolID, alternativeID).Scan(&found)
+	if err != nil {
+		return err
+	}
+	if found != 2 {
+		return ErrRecordNotFound
+	}
+
+	first, second := alternativePair(toolID, alternativeID)
+	query := `INSERT INTO tool_alternatives (tool_id, alternative_id)
+			  VALUES ($1, $2)
+			  ON CONFLICT DO NOTHING`
+
+	_, err = m.DB.ExecContext(ctx, query, first, second)
+	return err
+}
+
+func (m AlternativeModel) Remove(toolID, alternativeID int64) error {
+	first, second := alternativePair(toolID, alternativeID)
+	query := `DELETE FROM tool_alternatives WHERE tool_id = $1 AND alternative_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, first, second)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetAllForTool lists the tools pinned as alternatives of the given tool.
+// Unpublished alternatives are only included when published is false.
+func (m AlternativeModel) GetAllForTool(toolID int64, published bool) ([]*Tool, error) {
+	query := `SELECT t.id, t.created_at, t.name, t.slug, t.category, t.images, t.description, t.website, t.published
+			  FROM tool_alternatives a
+			  INNER JOIN tools t ON t.id = CASE WHEN a.tool_id = $1 THEN a.alternative_id ELSE a.tool_id END
+			  WHERE (a.tool_id = $1 OR a.alternative_id = $1) AND t.deleted_at IS NULL AND (t.published OR NOT $2)
+			  ORDER BY t.name`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, toolID, published)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tools := []*Tool{}
+	for rows.Next() {
+		var tool Tool
+		err := rows.Scan(
+			&tool.ID,
+			&tool.CreatedAt,
+			&tool.Name,
+			&tool.Slug,
+			&tool.Category,
+			&tool.Images,
+			&tool.Description,
+			&tool.Website,
+			&tool.Published,
+		)
+		if err != nil {
+			return nil, err
+		}
+		tools = append(tools, &tool)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tools, nil
+}
@@ -0,0 +1,77 @@
+package data
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestAlternativeModel_AddRemove(t *testing.T) {
+	tool := CreateTool(t)
+	alternative := CreateTool(t)
+
+	err := testQueries.Alternatives.Add(alternative.ID, tool.ID)
+	require.NoError(t, err)
+	err = testQueries.Alternatives.Add(tool.ID, alternative.ID)
+	require.NoError(t, err)
+
+	alternatives, err := testQueries.Alternatives.GetAllForTool(tool.ID, false)
+	require.NoError(t, err)
+	require.Equal(t, []int64{alternative.ID}, toolIDs(alternatives))
+
+	err = testQueries.Alternatives.Add(tool.ID, tool.ID)
+	require.ErrorIs(t, err, ErrSelfAlternative)
+
+	err = testQueries.Alternatives.Remove(tool.ID, alternative.ID)
+	require.NoError(t, err)
+	err = testQueries.Alternatives.Remove(tool.ID, alternative.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestToolModel_GetRelated(t *testing.T) {
+	tool := CreateTool(t)
+	sameCategory := CreateTool(t)
+	sameCategory.Category = tool.Category
+	sameCategory.Published = true
+	err := testQueries.Tools.Update(&sameCategory, 0)
+	require.NoError(t, err)
+
+	pinned := CreateTool(t)
+	pinned.Published = true
+	err = testQueries.Tools.Update(&pinned, 0)
+	require.NoError(t, err)
+	err = testQueries.Alternatives.Add(tool.ID, pinned.ID)
+	require.NoError(t, err)
+
+	weights := RelatedWeights{Category: 1, Favorites: 1, Text: 0.5}
+	related, err := testQueries.Tools.GetRelated(tool.ID, weights, 10)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(related), 2)
+	require.Equal(t, pinned.ID, related[0].ID)
+	require.True(t, related[0].Pinned)
+	require.Equal(t, sameCategory.ID, related[1].ID)
+	require.Equal(t, 1.0, related[1].Score)
+}
+
+func TestToolModel_GetRelated_SpecialCharacters(t *testing.T) {
+	tool := CreateTool(t)
+	tool.Description = `C:\tools\wdt isn't "quoted" & | ! <-> :*`
+	err := testQueries.Tools.Update(&tool, 0)
+	require.NoError(t, err)
+
+	match := CreateTool(t)
+	match.Description = `C:\tools\wdt isn't quoted`
+	match.Published = true
+	err = testQueries.Tools.Update(&match, 0)
+	require.NoError(t, err)
+
+	related, err := testQueries.Tools.GetRelated(tool.ID, RelatedWeights{Text: 1}, 10)
+	require.NoError(t, err)
+
+	found := false
+	for _, item := range related {
+		if item.ID == match.ID {
+			found = true
+		}
+	}
+	require.True(t, found)
+}
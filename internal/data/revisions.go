@@ -0,0 +1,210 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+type ToolRevisionModel struct {
+	DB *sql.DB
+}
+
+// ToolSnapshot is the editable state of a tool as stored with each revision.
+type ToolSnapshot struct {
+	Name        string        `json:"name"`
+	Slug        string        `json:"slug"`
+	Category    string        `json:"category"`
+	Description string        `json:"description"`
+	Images      ImageVariants `json:"images,omitempty"`
+	Website     string        `json:"website"`
+	Published   bool          `json:"published"`
+}
+
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+type ToolRevision struct {
+	ID          int64         `json:"id"`
+	ToolID      int64         `json:"toolId"`
+	Version     int64         `json:"version"`
+	EditorID    int64         `json:"editorId,omitempty"`
+	EditorName  string        `json:"editorName,omitempty"`
+	EditorEmail string        `json:"editorEmail,omitempty"`
+	CreatedAt   time.Time     `json:"createdAt"`
+	Snapshot    ToolSnapshot  `json:"snapshot"`
+	Changes     []FieldChange `json:"changes,omitempty"`
+}
+
+func snapshotTool(tool *Tool) ToolSnapshot {
+	return ToolSnapshot{
+		Name:        tool.Name,
+		Slug:        tool.Slug,
+		Category:    tool.Category,
+		Description: tool.Description,
+		Images:      tool.Images,
+		Website:     tool.Website,
+		Published:   tool.Published,
+	}
+}
+
+// Apply copies the snapshot's fields onto tool, leaving its identity and
+// version untouched. The slug is not restored; it follows the name on update.
+func (s ToolSnapshot) Apply(tool *Tool) {
+	tool.Name = s.Name
+	tool.Category = s.Category
+	tool.Description = s.Description
+	tool.Images = s.Images
+	tool.Website = s.Website
+	tool.Published = s.Published
+}
+
+// diffSnapshots lists the fields that differ between two snapshots.
+func diffSnapshots(from, to ToolSnapshot) []FieldChange {
+	changes := []FieldChange{}
+
+	add := func(field string, a, b interface{}) {
+		if a != b {
+			changes = append(changes, FieldChange{Field: field, From: a, To: b})
+		}
+	}
+
+	add("name", from.Name, to.Name)
+	add("slug", from.Slug, to.Slug)
+	add("category", from.Category, to.Category)
+	add("description", from.Description, to.Description)
+	if !sameImages(from.Images, to.Images) {
+		changes = append(changes, FieldChange{Field: "images", From: from.Images, To: to.Images})
+	}
+	add("website", from.Website, to.Website)
+	add("published", from.Published, to.Published)
+
+	return changes
+}
+
+func sameImages(a, b ImageVariants) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// insertToolRevision records the stored state of a tool under its current
+// version. An existing revision for that version is left untouched.
+func insertToolRevision(ctx context.Context, q querier, tool *Tool, editorID int64) error {
+	query := `INSERT INTO tool_revisions (tool_id, version, editor_id, snapshot)
+			  SELECT id, version, $2, $3 FROM tools WHERE id = $1
+			  ON CONFLICT (tool_id, version) DO NOTHING`
+
+	snapshot, err := json.Marshal(snapshotTool(tool))
+	if err != nil {
+		return err
+	}
+
+	_, err = q.ExecContext(ctx, query, tool.ID, newNullInt64(editorID), snapshot)
+	return err
+}
+
+// GetAllForTool returns every revision of a tool, newest first, each with the
+// changes it made relative to the revision before it.
+func (m ToolRevisionModel) GetAllForTool(toolID int64) ([]*ToolRevision, error) {
+	query := `SELECT r.id, r.tool_id, r.version, coalesce(r.editor_id, 0), coalesce(u.name, ''), coalesce(u.email, ''), r.created_at, r.snapshot
+			  FROM tool_revisions r
+			  LEFT JOIN users u ON u.id = r.editor_id
+			  WHERE r.tool_id = $1
+			  ORDER BY r.version ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, toolID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*ToolRevision{}
+
+	for rows.Next() {
+		revision, err := scanToolRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(revisions) > 0 {
+			revision.Changes = diffSnapshots(revisions[len(revisions)-1].Snapshot, revision.Snapshot)
+		} else {
+			revision.Changes = diffSnapshots(ToolSnapshot{}, revision.Snapshot)
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(revisions)-1; i < j; i, j = i+1, j-1 {
+		revisions[i], revisions[j] = revisions[j], revisions[i]
+	}
+
+	return revisions, nil
+}
+
+func (m ToolRevisionModel) Get(toolID, version int64) (*ToolRevision, error) {
+	query := `SELECT r.id, r.tool_id, r.version, coalesce(r.editor_id, 0), coalesce(u.name, ''), coalesce(u.email, ''), r.created_at, r.snapshot
+			  FROM tool_revisions r
+			  LEFT JOIN users u ON u.id = r.editor_id
+			  WHERE r.tool_id = $1 AND r.version = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	revision, err := scanToolRevision(m.DB.QueryRowContext(ctx, query, toolID, version))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return revision, nil
+}
+
+func scanToolRevision(row interface{ Scan(dest ...any) error }) (*ToolRevision, error) {
+	var revision ToolRevision
+	var snapshot []byte
+
+	err := row.Scan(
+		&revision.ID,
+		&revision.ToolID,
+		&revision.Version,
+		&revision.EditorID,
+		&revision.EditorName,
+		&revision.EditorEmail,
+		&revision.CreatedAt,
+		&snapshot,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(snapshot, &revision.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
@@ -0,0 +1,51 @@
+package data
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	from := ToolSnapshot{Name: "Prisma", Category: "DB", Published: true}
+	to := ToolSnapshot{Name: "Prisma ORM", Category: "DB", Published: false}
+
+	changes := diffSnapshots(from, to)
+	require.Len(t, changes, 2)
+	require.Equal(t, FieldChange{Field: "name", From: "Prisma", To: "Prisma ORM"}, changes[0])
+	require.Equal(t, FieldChange{Field: "published", From: true, To: false}, changes[1])
+}
+
+func TestToolRevisionModel_GetAllForTool(t *testing.T) {
+	user := CreateRandomUser(t)
+	tool := CreateTool(t)
+
+	tool.Description = "updated"
+	err := testQueries.Tools.Update(&tool, user.ID)
+	require.NoError(t, err)
+
+	revisions, err := testQueries.Revisions.GetAllForTool(tool.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+
+	latest := revisions[0]
+	require.Equal(t, user.ID, latest.EditorID)
+	require.Equal(t, "updated", latest.Snapshot.Description)
+	require.Len(t, latest.Changes, 1)
+	require.Equal(t, "description", latest.Changes[0].Field)
+}
+
+func TestToolRevisionModel_Get(t *testing.T) {
+	tool := CreateTool(t)
+
+	tool.Description = "updated"
+	err := testQueries.Tools.Update(&tool, 0)
+	require.NoError(t, err)
+
+	revision, err := testQueries.Revisions.Get(tool.ID, 1)
+	require.NoError(t, err)
+	require.Equal(t, tool.Name, revision.Snapshot.Name)
+	require.NotEqual(t, "updated", revision.Snapshot.Description)
+
+	_, err = testQueries.Revisions.Get(tool.ID, 100)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
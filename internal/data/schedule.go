@@ -0,0 +1,267 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	validator "github.com/wdt/internal/validators"
+)
+
+func ValidateSchedule(v *validator.Validator, publishAt, unpublishAt *time.Time) {
+	if publishAt != nil && unpublishAt != nil {
+		v.Check(unpublishAt.After(*publishAt), "unpublishAt", "must be after publishAt")
+	}
+}
+
+// SetSchedule stores when a tool should be published and unpublished. A nil
+// time clears that side of the schedule. It returns ErrEditConflict when the
+// tool changed since tool.Version.
+func (m ToolModel) SetSchedule(tool *Tool) error {
+	query := `UPDATE tools
+			  SET publish_at = $1, unpublish_at = $2, version = version + 1
+			  WHERE id = $3 AND version = $4 AND deleted_at IS NULL
+			  RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, tool.PublishAt, tool.UnpublishAt, tool.ID, tool.Version).Scan(&tool.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetScheduled lists tools with a pending schedule, soonest change first.
+func (m ToolModel) GetScheduled() ([]*Tool, error) {
+	query := `SELECT id, name, slug, category, published, publish_at, unpublish_at
+			  FROM tools
+			  WHERE (publish_at IS NOT NULL OR unpublish_at IS NOT NULL) AND deleted_at IS NULL
+			  ORDER BY LEAST(publish_at, unpublish_at), id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tools := []*Tool{}
+
+	for rows.Next() {
+		var tool Tool
+
+		err := rows.Scan(
+			&tool.ID,
+			&tool.Name,
+			&tool.Slug,
+			&tool.Category,
+			&tool.Published,
+			&tool.PublishAt,
+			&tool.UnpublishAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		tools = append(tools, &tool)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tools, nil
+}
+
+// ApplySchedule publishes and unpublishes every tool whose scheduled time has
+// passed, recording each change as a revision. It returns the number of
+// changes made.
+func (m ToolModel) ApplySchedule() (int, error) {
+	queries := []string{
+		`UPDATE tools
+		 SET published = true, publish_at = NULL, version = version + 1
+		 WHERE publish_at <= NOW() AND deleted_at IS NULL
+		 RETURNING id, created_at, name, slug, category, description, published, website, images, version`,
+		`UPDATE tools
+		 SET published = false, unpublish_at = NULL, version = version + 1
+		 WHERE unpublish_at <= NOW() AND deleted_at IS NULL
+		 RETURNING id, created_at, name, slug, category, description, published, website, images, version`,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	changes := 0
+	for _, query := range queries {
+		tools, err := scanScheduledTools(ctx, tx, query)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, tool := range tools {
+			err = insertToolRevision(ctx, tx, tool, 0)
+			if err != nil {
+				return 0, err
+			}
+		}
+		changes += len(tools)
+	}
+
+	return changes, tx.Commit()
+}
+
+func scanScheduledTools(ctx context.Context, q querier, query string) ([]*Tool, error) {
+	rows, err := q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tools []*Tool
+
+	for rows.Next() {
+		var tool Tool
+
+		err := rows.Scan(
+			&tool.ID,
+			&tool.CreatedAt,
+			&tool.Name,
+			&tool.Slug,
+			&tool.Category,
+			&tool.Description,
+			&tool.Published,
+			&tool.Website,
+			&tool.Images,
+			&tool.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		tools = append(tools, &tool)
+	}
+
+	return tools, rows.Err()
+}
+
+// SetSchedule stores when a category should be published and unpublished. A
+// nil time clears that side of the schedule. It returns ErrEditConflict when
+// the category changed since category.Version.
+func (m CategoryModel) SetSchedule(category *Category) error {
+	query := `UPDATE categories
+			  SET publish_at = $1, unpublish_at = $2, version = version + 1
+			  WHERE id = $3 AND version = $4 AND deleted_at IS NULL
+			  RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, category.PublishAt, category.UnpublishAt, category.ID, category.Version).Scan(&category.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetScheduled lists categories with a pending schedule, soonest change first.
+func (m CategoryModel) GetScheduled() ([]*Category, error) {
+	query := `SELECT id, name, published, publish_at, unpublish_at
+			  FROM categories
+			  WHERE (publish_at IS NOT NULL OR unpublish_at IS NOT NULL) AND deleted_at IS NULL
+			  ORDER BY LEAST(publish_at, unpublish_at), id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []*Category{}
+
+	for rows.Next() {
+		var category Category
+
+		err := rows.Scan(
+			&category.ID,
+			&category.Name,
+			&category.Published,
+			&category.PublishAt,
+			&category.UnpublishAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		categories = append(categories, &category)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+// ApplySchedule publishes and unpublishes every category whose scheduled
+// time has passed and returns the number of changes made.
+func (m CategoryModel) ApplySchedule() (int, error) {
+	queries := []string{
+		`UPDATE categories
+		 SET published = true, publish_at = NULL, version = version + 1
+		 WHERE publish_at <= NOW() AND deleted_at IS NULL`,
+		`UPDATE categories
+		 SET published = false, unpublish_at = NULL, version = version + 1
+		 WHERE unpublish_at <= NOW() AND deleted_at IS NULL`,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	changes := 0
+	for _, query := range queries {
+		result, err := tx.ExecContext(ctx, query)
+		if err != nil {
+			return 0, err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		changes += int(rowsAffected)
+	}
+
+	return changes, tx.Commit()
+}
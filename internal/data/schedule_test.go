@@ -0,0 +1,72 @@
+package data
+
+import (
+	"github.com/stretchr/testify/require"
+	validator "github.com/wdt/internal/validators"
+	"testing"
+	"time"
+)
+
+func TestValidateSchedule(t *testing.T) {
+	publishAt := time.Now().Add(time.Hour)
+	unpublishAt := publishAt.Add(-time.Minute)
+
+	v := validator.New()
+	ValidateSchedule(v, &publishAt, &unpublishAt)
+	require.Contains(t, v.Errors, "unpublishAt")
+
+	v = validator.New()
+	ValidateSchedule(v, &publishAt, nil)
+	require.True(t, v.Valid())
+}
+
+func TestToolModel_ApplySchedule(t *testing.T) {
+	tool := CreateTool(t)
+
+	publishAt := time.Now().Add(-time.Minute)
+	tool.PublishAt = &publishAt
+	err := testQueries.Tools.SetSchedule(&tool)
+	require.NoError(t, err)
+
+	scheduled, err := testQueries.Tools.GetScheduled()
+	require.NoError(t, err)
+	require.Contains(t, toolIDs(scheduled), tool.ID)
+
+	_, err = testQueries.Tools.ApplySchedule()
+	require.NoError(t, err)
+
+	dbTool, err := testQueries.Tools.Get(tool.ID)
+	require.NoError(t, err)
+	require.True(t, dbTool.Published)
+	require.Nil(t, dbTool.PublishAt)
+}
+
+func TestCategoryModel_ApplySchedule(t *testing.T) {
+	category := CreateCategory(t)
+
+	unpublishAt := time.Now().Add(-time.Minute)
+	category.UnpublishAt = &unpublishAt
+	err := testQueries.Categories.SetSchedule(&category)
+	require.NoError(t, err)
+
+	_, err = testQueries.Categories.ApplySchedule()
+	require.NoError(t, err)
+
+	dbCategory, err := testQueries.Categories.Get(category.ID)
+	require.NoError(t, err)
+	require.False(t, dbCategory.Published)
+	require.Nil(t, dbCategory.UnpublishAt)
+}
+
+func TestToolModel_SetScheduleStaleVersion(t *testing.T) {
+	tool := CreateTool(t)
+
+	publishAt := time.Now().Add(time.Hour)
+	tool.PublishAt = &publishAt
+	err := testQueries.Tools.SetSchedule(&tool)
+	require.NoError(t, err)
+
+	tool.Version--
+	err = testQueries.Tools.SetSchedule(&tool)
+	require.ErrorIs(t, err, ErrEditConflict)
+}
@@ -0,0 +1,83 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	EmailEventBounce    = "bounce"
+	EmailEventComplaint = "complaint"
+)
+
+// EmailEvent is a bounce or complaint the mail provider reported for an
+// address. WebhookID identifies the delivery it came with so a redelivered
+// webhook is only recorded once.
+type EmailEvent struct {
+	ID         int64     `json:"id"`
+	WebhookID  string    `json:"-"`
+	Email      string    `json:"email"`
+	UserID     int64     `json:"userId,omitempty"`
+	Type       string    `json:"type"`
+	BounceType string    `json:"bounceType,omitempty"`
+	Detail     string    `json:"detail,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type SuppressionModel struct {
+	DB *sql.DB
+}
+
+// RecordEvent stores event against the user with its address, if any; the
+// oldest one when the address differs only in case between users. It
+// returns false when the event was already recorded.
+func (m SuppressionModel) RecordEvent(event *EmailEvent) (bool, error) {
+	query := `INSERT INTO email_events (webhook_id, email, user_id, type, bounce_type, detail)
+			  VALUES ($1, $2, (SELECT id FROM users WHERE lower(email) = $2 ORDER BY id LIMIT 1), $3, $4, $5)
+			  ON CONFLICT (webhook_id, email) DO NOTHING
+			  RETURNING id, coalesce(user_id, 0), created_at`
+
+	event.Email = strings.ToLower(event.Email)
+	args := []interface{}{event.WebhookID, event.Email, event.Type, event.BounceType, event.Detail}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&event.ID, &event.UserID, &event.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Suppress stops all further email to the address.
+func (m SuppressionModel) Suppress(email, reason string) error {
+	query := `INSERT INTO email_suppressions (email, reason)
+			  VALUES ($1, $2)
+			  ON CONFLICT (email) DO NOTHING`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, strings.ToLower(email), reason)
+	return err
+}
+
+// IsSuppressed reports whether email to the address is suppressed.
+func (m SuppressionModel) IsSuppressed(email string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM email_suppressions WHERE email = $1)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var suppressed bool
+	err := m.DB.QueryRowContext(ctx, query, strings.ToLower(email)).Scan(&suppressed)
+	return suppressed, err
+}
@@ -0,0 +1,65 @@
+package data
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/wdt/internal/random"
+	"strings"
+	"testing"
+)
+
+func TestSuppressionModel_RecordEvent(t *testing.T) {
+	user := CreateRandomUser(t)
+	event := &EmailEvent{
+		WebhookID:  "msg_" + random.RandString(10),
+		Email:      strings.ToUpper(user.Email),
+		Type:       EmailEventBounce,
+		BounceType: "Permanent",
+	}
+
+	recorded, err := testQueries.Suppressions.RecordEvent(event)
+	require.NoError(t, err)
+	require.True(t, recorded)
+	require.Equal(t, user.ID, event.UserID)
+
+	again := *event
+	recorded, err = testQueries.Suppressions.RecordEvent(&again)
+	require.NoError(t, err)
+	require.False(t, recorded)
+}
+
+func TestSuppressionModel_RecordEventCaseVariants(t *testing.T) {
+	// Addresses are only unique case-sensitively, so several users may
+	// match the event.
+	user := CreateRandomUser(t)
+	other := &User{Email: strings.ToUpper(user.Email), Name: random.RandString(10)}
+	err := testQueries.Users.Insert(other)
+	require.NoError(t, err)
+
+	event := &EmailEvent{
+		WebhookID: "msg_" + random.RandString(10),
+		Email:     user.Email,
+		Type:      EmailEventComplaint,
+	}
+
+	recorded, err := testQueries.Suppressions.RecordEvent(event)
+	require.NoError(t, err)
+	require.True(t, recorded)
+	require.Equal(t, user.ID, event.UserID)
+}
+
+func TestSuppressionModel_Suppress(t *testing.T) {
+	email := random.RandString(10) + "@gmail.com"
+
+	suppressed, err := testQueries.Suppressions.IsSuppressed(email)
+	require.NoError(t, err)
+	require.False(t, suppressed)
+
+	err = testQueries.Suppressions.Suppress(strings.ToUpper(email), EmailEventBounce)
+	require.NoError(t, err)
+	err = testQueries.Suppressions.Suppress(email, EmailEventBounce)
+	require.NoError(t, err)
+
+	suppressed, err = testQueries.Suppressions.IsSuppressed(email)
+	require.NoError(t, err)
+	require.True(t, suppressed)
+}
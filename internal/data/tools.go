@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	validator "github.com/wdt/internal/validators"
@@ -15,16 +16,22 @@ type ToolModel struct {
 }
 
 type Tool struct {
-	ID          int64     `json:"id"`
-	CreatedAt   time.Time `json:"createdAt"`
-	Name        string    `json:"name"`
-	Category    string    `json:"category"`
-	Description string    `json:"description"`
-	ImageUrl    string    `json:"imageUrl"`
-	Published   bool      `json:"published,omitempty"`
-	Website     string    `json:"website"`
-	Version     int64     `json:"version,omitempty"`
-	Favorite    bool      `json:"favorite,omitempty"`
+	ID          int64         `json:"id"`
+	CreatedAt   time.Time     `json:"createdAt"`
+	Name        string        `json:"name"`
+	Slug        string        `json:"slug"`
+	Category    string        `json:"category"`
+	Description string        `json:"description"`
+	Images      ImageVariants `json:"images,omitempty"`
+	Published   bool          `json:"published,omitempty"`
+	Website     string        `json:"website"`
+	Version     int64         `json:"version,omitempty"`
+	Favorite    bool          `json:"favorite,omitempty"`
+	PublishAt   *time.Time    `json:"publishAt,omitempty"`
+	UnpublishAt *time.Time    `json:"unpublishAt,omitempty"`
+	DeletedAt   *time.Time    `json:"deletedAt,omitempty"`
+
+	WebsiteHealth *WebsiteHealth `json:"websiteHealth,omitempty"`
 }
 
 func ValidateTools(v *validator.Validator, tool *Tool) {
@@ -33,26 +40,114 @@ func ValidateTools(v *validator.Validator, tool *Tool) {
 	v.Check(tool.Category != "", "category", "must be provided")
 	v.Check(len(tool.Category) <= 40, "category", "must not be more than 500 bytes long")
 	v.Check(len(tool.Description) <= 160, "description", "must not be more than 5000 bytes long")
+	ValidateImageVariants(v, tool.Images)
 }
+
+// slugify lowercases s and collapses every run of characters outside [a-z0-9]
+// into a single dash, mirroring the backfill in the tool-slugs migration.
+func slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			dash = false
+		case b.Len() > 0 && !dash:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+
+	slug := strings.TrimSuffix(b.String(), "-")
+	if slug == "" {
+		return "tool"
+	}
+	return slug
+}
+
+// slugMatchesName reports whether slug was generated from name, either as is
+// or with a numeric suffix added to keep it unique.
+func slugMatchesName(slug, name string) bool {
+	base := slugify(name)
+	if slug == base {
+		return true
+	}
+
+	suffix, found := strings.CutPrefix(slug, base+"-")
+	if !found || suffix == "" {
+		return false
+	}
+	for _, r := range suffix {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// uniqueSlug returns the first slug derived from name that is not used by
+// another tool, either as its current slug or as one of its old ones.
+func uniqueSlug(ctx context.Context, q querier, name string, toolID int64) (string, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM tools WHERE slug = $1 AND id <> $2)
+			  OR EXISTS (SELECT 1 FROM tool_slug_redirects WHERE slug = $1 AND tool_id <> $2)`
+
+	base := slugify(name)
+	slug := base
+	for i := 2; ; i++ {
+		var taken bool
+		err := q.QueryRowContext(ctx, query, slug, toolID).Scan(&taken)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return slug, nil
+		}
+		slug = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
 func (m ToolModel) Insert(tool *Tool) error {
-	query := `INSERT INTO tools (name, category, image_url, description, published, website)
-			 VALUES ($1, $2 ,$3, $4, $5, $6)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = insertTool(ctx, tx, tool)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertTool(ctx context.Context, q querier, tool *Tool) error {
+	query := `INSERT INTO tools (name, slug, category, description, published, website, images)
+			 VALUES ($1, $2 ,$3, $4, $5, $6, $7)
 			 RETURNING id, created_at, version
 			`
 
+	slug, err := uniqueSlug(ctx, q, tool.Name, 0)
+	if err != nil {
+		return err
+	}
+	tool.Slug = slug
+
 	args := []interface{}{
 		tool.Name,
+		tool.Slug,
 		tool.Category,
-		NewNullString(tool.ImageUrl),
 		tool.Description,
 		tool.Published,
 		tool.Website,
+		tool.Images,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+	err = q.QueryRowContext(ctx, query, args...).Scan(
 		&tool.ID,
 		&tool.CreatedAt,
 		&tool.Version,
@@ -62,27 +157,58 @@ func (m ToolModel) Insert(tool *Tool) error {
 		return err
 	}
 
-	return nil
+	return linkToolUploads(ctx, q, tool)
 }
 
 func (m ToolModel) Get(id int64) (*Tool, error) {
-	query := `SELECT id, created_at, name, category, coalesce(image_url, ''), description, published, version
+	query := `SELECT id, created_at, name, slug, category, description, published, website, version, publish_at, unpublish_at, images
 			  FROM tools
-			  WHERE id = $1`
+			  WHERE id = $1 AND deleted_at IS NULL`
 
-	var tool Tool
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+	return getTool(ctx, m.DB, query, id)
+}
+
+func (m ToolModel) GetBySlug(slug string) (*Tool, error) {
+	query := `SELECT id, created_at, name, slug, category, description, published, website, version, publish_at, unpublish_at, images
+			  FROM tools
+			  WHERE slug = $1 AND deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return getTool(ctx, m.DB, query, slug)
+}
+
+// lockTool reads the stored state of a tool and holds a row lock on it until
+// the surrounding transaction ends.
+func lockTool(ctx context.Context, q querier, id int64) (*Tool, error) {
+	query := `SELECT id, created_at, name, slug, category, description, published, website, version, publish_at, unpublish_at, images
+			  FROM tools
+			  WHERE id = $1 AND deleted_at IS NULL
+			  FOR UPDATE`
+
+	return getTool(ctx, q, query, id)
+}
+
+func getTool(ctx context.Context, q querier, query string, args ...any) (*Tool, error) {
+	var tool Tool
+
+	err := q.QueryRowContext(ctx, query, args...).Scan(
 		&tool.ID,
 		&tool.CreatedAt,
 		&tool.Name,
+		&tool.Slug,
 		&tool.Category,
-		&tool.ImageUrl,
 		&tool.Description,
 		&tool.Published,
+		&tool.Website,
 		&tool.Version,
+		&tool.PublishAt,
+		&tool.UnpublishAt,
+		&tool.Images,
 	)
 	if err != nil {
 		switch {
@@ -96,42 +222,211 @@ func (m ToolModel) Get(id int64) (*Tool, error) {
 	return &tool, nil
 }
 
+// GetRedirectSlug looks up a slug a tool used before it was renamed and
+// returns the tool's current slug.
+func (m ToolModel) GetRedirectSlug(slug string) (string, error) {
+	query := `SELECT t.slug
+			  FROM tool_slug_redirects r
+			  INNER JOIN tools t ON t.id = r.tool_id
+			  WHERE r.slug = $1 AND t.deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var current string
+	err := m.DB.QueryRowContext(ctx, query, slug).Scan(&current)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return "", ErrRecordNotFound
+		default:
+			return "", err
+		}
+	}
+
+	return current, nil
+}
+
+// Delete moves a tool to the trash. It stays restorable until PurgeDeleted
+// removes it for good.
 func (m ToolModel) Delete(id int64) error {
-	query := `DELETE FROM tools WHERE id = $1`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return deleteTool(ctx, m.DB, id)
+}
+
+func deleteTool(ctx context.Context, q querier, id int64) error {
+	query := `UPDATE tools SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
+
+	result, err := q.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m ToolModel) Restore(id int64) error {
+	query := `UPDATE tools SET deleted_at = NULL, version = version + 1 WHERE id = $1 AND deleted_at IS NOT NULL`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	_, err := m.DB.ExecContext(ctx, query, id)
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
 
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
 
 	return nil
 }
 
-func (m ToolModel) Update(tool *Tool) error {
+// GetDeleted lists the tools in the trash, most recently deleted first.
+func (m ToolModel) GetDeleted() ([]*Tool, error) {
+	query := `SELECT id, created_at, name, slug, category, images, description, published, website, version, deleted_at
+			  FROM tools
+			  WHERE deleted_at IS NOT NULL
+			  ORDER BY deleted_at DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tools := []*Tool{}
+
+	for rows.Next() {
+		var tool Tool
+
+		err := rows.Scan(
+			&tool.ID,
+			&tool.CreatedAt,
+			&tool.Name,
+			&tool.Slug,
+			&tool.Category,
+			&tool.Images,
+			&tool.Description,
+			&tool.Published,
+			&tool.Website,
+			&tool.Version,
+			&tool.DeletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		tools = append(tools, &tool)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tools, nil
+}
+
+// PurgeDeleted permanently removes tools that have been in the trash for
+// longer than retention and returns how many were removed.
+func (m ToolModel) PurgeDeleted(retention time.Duration) (int64, error) {
+	query := `DELETE FROM tools WHERE deleted_at < NOW() - make_interval(secs => $1)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, retention.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// Update saves tool and records the result as a new revision attributed to
+// editorID. When the name no longer matches the slug a new slug is generated
+// and the old one is kept as a redirect.
+func (m ToolModel) Update(tool *Tool, editorID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = updateTool(ctx, tx, tool, editorID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// updateTool does the work of Update inside the caller's transaction.
+func updateTool(ctx context.Context, tx *sql.Tx, tool *Tool, editorID int64) error {
 	query := `UPDATE tools
-			  SET name = $1, category = $2, image_url = $3, description = $4, published = $5, version = version + 1
-			  WHERE id = $6 AND version = $7
+			  SET name = $1, slug = $2, category = $3, description = $4, published = $5, website = $6, images = $7,
+			      version = version + 1
+			  WHERE id = $8 AND version = $9 AND deleted_at IS NULL
 			  RETURNING version
 			  `
 
+	previous, err := lockTool(ctx, tx, tool.ID)
+	if err != nil {
+		return err
+	}
+	if previous.Version != tool.Version {
+		return ErrEditConflict
+	}
+
+	// Tools that predate revision history get their current state recorded
+	// first so the new revision has something to be compared against.
+	err = insertToolRevision(ctx, tx, previous, 0)
+	if err != nil {
+		return err
+	}
+
+	previousSlug := previous.Slug
+	if !slugMatchesName(tool.Slug, tool.Name) {
+		tool.Slug, err = uniqueSlug(ctx, tx, tool.Name, tool.ID)
+		if err != nil {
+			return err
+		}
+	}
+
 	args := []interface{}{
 		tool.Name,
+		tool.Slug,
 		tool.Category,
-		NewNullString(tool.ImageUrl),
 		tool.Description,
 		tool.Published,
+		tool.Website,
+		tool.Images,
 		tool.ID,
 		tool.Version,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	_, err := m.DB.ExecContext(ctx, query, args...)
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&tool.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -141,26 +436,82 @@ func (m ToolModel) Update(tool *Tool) error {
 		}
 	}
 
-	return nil
-}
+	if previousSlug != tool.Slug {
+		err = addSlugRedirect(ctx, tx, previousSlug, tool.Slug, tool.ID)
+		if err != nil {
+			return err
+		}
+	}
 
-func (m ToolModel) GetAll(filters Filters, search string) ([]*Tool, Metadata, error) {
-	baseQuery := `SELECT count(*) OVER(), id, created_at, name, category, coalesce(image_url, ''), description, published, website
-              FROM tools`
+	err = linkToolUploads(ctx, tx, tool)
+	if err != nil {
+		return err
+	}
 
-	advQuery := fmt.Sprintf(` ORDER BY %s %s`, filters.sortColumn(), filters.sortDirection())
+	return insertToolRevision(ctx, tx, tool, editorID)
+}
 
-	if search != "" {
-		baseQuery += ` WHERE name ILIKE '%' || $3 || '%' OR category ILIKE '%' || $3 || '%'`
+func addSlugRedirect(ctx context.Context, q querier, oldSlug, newSlug string, toolID int64) error {
+	query := `INSERT INTO tool_slug_redirects (slug, tool_id)
+			  VALUES ($1, $2)
+			  ON CONFLICT (slug) DO UPDATE SET tool_id = EXCLUDED.tool_id, created_at = NOW()`
+
+	_, err := q.ExecContext(ctx, query, oldSlug, toolID)
+	if err != nil {
+		return err
 	}
 
-	query := baseQuery + advQuery + ` LIMIT $1 OFFSET $2`
+	// A tool renamed back to an earlier name takes its old slug over again.
+	_, err = q.ExecContext(ctx, `DELETE FROM tool_slug_redirects WHERE slug = $1`, newSlug)
+	return err
+}
+
+// categorySubtreeFilter restricts a tool query to the category named by
+// argument $n and all of its subcategories. It returns the CTE to put in front
+// of the query and the condition to add to its WHERE clause.
+func categorySubtreeFilter(n int) (string, string) {
+	cte := fmt.Sprintf(`WITH RECURSIVE subtree AS (
+				SELECT id, name FROM categories WHERE name = $%[1]d AND deleted_at IS NULL
+				UNION
+				SELECT c.id, c.name FROM categories c INNER JOIN subtree s ON c.parent_id = s.id WHERE c.deleted_at IS NULL
+			) `, n)
+	condition := fmt.Sprintf(` AND (category = $%[1]d OR category IN (SELECT name FROM subtree))`, n)
+
+	return cte, condition
+}
+
+// GetAll lists tools for admins along with the latest website check. A
+// non-empty category limits the list to that category and its
+// subcategories, and flagged to tools whose website keeps failing.
+func (m ToolModel) GetAll(filters Filters, search, category string, flagged bool) ([]*Tool, Metadata, error) {
+	baseQuery := `SELECT count(*) OVER(), id, created_at, name, slug, category, images, description, published, website,
+              w.checked_at, coalesce(w.ok, false), coalesce(w.status_code, 0), coalesce(w.latency_ms, 0), coalesce(w.redirect_url, ''),
+              coalesce(w.error, ''), w.failing_since, coalesce(w.flagged, false)
+              FROM tools
+              LEFT JOIN website_checks w ON w.tool_id = tools.id
+              WHERE deleted_at IS NULL`
+
+	advQuery := fmt.Sprintf(` ORDER BY %s %s`, filters.sortColumn(), filters.sortDirection())
+
 	args := []interface{}{filters.limit(), filters.offset()}
+
 	if search != "" {
 		args = append(args, search)
+		baseQuery += fmt.Sprintf(` AND (name ILIKE '%%' || $%[1]d || '%%' OR category ILIKE '%%' || $%[1]d || '%%')`, len(args))
 	}
 
-	fmt.Println(query)
+	if category != "" {
+		args = append(args, category)
+		cte, condition := categorySubtreeFilter(len(args))
+		baseQuery = cte + baseQuery + condition
+	}
+
+	if flagged {
+		baseQuery += ` AND w.flagged`
+	}
+
+	query := baseQuery + advQuery + ` LIMIT $1 OFFSET $2`
+
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
@@ -175,23 +526,39 @@ func (m ToolModel) GetAll(filters Filters, search string) ([]*Tool, Metadata, er
 
 	for rows.Next() {
 		var tool Tool
+		var health WebsiteHealth
+		var checkedAt *time.Time
 
 		err := rows.Scan(
 			&totalRecords,
 			&tool.ID,
 			&tool.CreatedAt,
 			&tool.Name,
+			&tool.Slug,
 			&tool.Category,
-			&tool.ImageUrl,
+			&tool.Images,
 			&tool.Description,
 			&tool.Published,
 			&tool.Website,
+			&checkedAt,
+			&health.OK,
+			&health.StatusCode,
+			&health.LatencyMs,
+			&health.RedirectURL,
+			&health.Error,
+			&health.FailingSince,
+			&health.Flagged,
 		)
 
 		if err != nil {
 			return nil, Metadata{}, err
 		}
 
+		if checkedAt != nil {
+			health.CheckedAt = *checkedAt
+			tool.WebsiteHealth = &health
+		}
+
 		tools = append(tools, &tool)
 	}
 
@@ -203,25 +570,30 @@ func (m ToolModel) GetAll(filters Filters, search string) ([]*Tool, Metadata, er
 	return tools, metadata, nil
 }
 
-func (m ToolModel) GetAllPublished(search string, filters Filters) ([]*Tool, Metadata, error) {
-	baseQuery := `SELECT count(*) OVER(), id, created_at, name, category, coalesce(image_url, ''), description, website
+// GetAllPublished lists published tools. A non-empty category limits the
+// list to that category and its subcategories.
+func (m ToolModel) GetAllPublished(search, category string, filters Filters) ([]*Tool, Metadata, error) {
+	baseQuery := `SELECT count(*) OVER(), id, created_at, name, slug, category, images, description, website
 			  FROM tools 
-			  WHERE published = true`
+			  WHERE published = true AND deleted_at IS NULL`
+
+	args := []interface{}{filters.limit(), filters.offset()}
 
 	if search != "" {
-		baseQuery += ` AND (name ILIKE '%' || $3 || '%' OR category ILIKE '%' || $3 || '%')`
+		args = append(args, search)
+		baseQuery += fmt.Sprintf(` AND (name ILIKE '%%' || $%[1]d || '%%' OR category ILIKE '%%' || $%[1]d || '%%')`, len(args))
+	}
+
+	if category != "" {
+		args = append(args, category)
+		cte, condition := categorySubtreeFilter(len(args))
+		baseQuery = cte + baseQuery + condition
 	}
 
 	advQuery := fmt.Sprintf(` ORDER BY %s %s`, filters.sortColumn(), filters.sortDirection())
 
 	query := baseQuery + advQuery + ` LIMIT $1 OFFSET $2`
 
-	args := []interface{}{filters.limit(), filters.offset()}
-
-	if search != "" {
-		args = append(args, search)
-	}
-
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
@@ -242,8 +614,9 @@ func (m ToolModel) GetAllPublished(search string, filters Filters) ([]*Tool, Met
 			&tool.ID,
 			&tool.CreatedAt,
 			&tool.Name,
+			&tool.Slug,
 			&tool.Category,
-			&tool.ImageUrl,
+			&tool.Images,
 			&tool.Description,
 			&tool.Website,
 		)
@@ -2,8 +2,10 @@ package data
 
 import (
 	"github.com/stretchr/testify/require"
+	"github.com/wdt/internal/random"
 	validator "github.com/wdt/internal/validators"
 	"testing"
+	"time"
 )
 
 func TestValidateTools(t *testing.T) {
@@ -57,7 +59,7 @@ func TestToolModel_Update(t *testing.T) {
 	tool.Category = "updated"
 	tool.Description = "updated"
 
-	err := testQueries.Tools.Update(&tool)
+	err := testQueries.Tools.Update(&tool, 0)
 	require.NoError(t, err)
 
 	dbTool, err := testQueries.Tools.Get(tool.ID)
@@ -69,6 +71,26 @@ func TestToolModel_Update(t *testing.T) {
 	require.Equal(t, tool.Description, dbTool.Description)
 }
 
+func TestToolModel_Update_Images(t *testing.T) {
+	tool := CreateTool(t)
+	require.Empty(t, tool.Images)
+
+	tool.Website = "https://example.com"
+	tool.Images = ImageVariants{
+		{Name: "original", Format: "png", Width: 800, Height: 600, URL: "https://cdn.example.com/original.png"},
+		{Name: "64", Format: "webp", Width: 64, Height: 48, URL: "https://cdn.example.com/64.webp"},
+	}
+
+	err := testQueries.Tools.Update(&tool, 0)
+	require.NoError(t, err)
+
+	dbTool, err := testQueries.Tools.Get(tool.ID)
+	require.NoError(t, err)
+	require.Equal(t, tool.Website, dbTool.Website)
+	require.Equal(t, tool.Images, dbTool.Images)
+	require.Equal(t, "https://cdn.example.com/original.png", dbTool.Images.Original())
+}
+
 func TestToolModel_GetAll(t *testing.T) {
 	for i := 0; i < 10; i++ {
 		CreateTool(t)
@@ -82,7 +104,7 @@ func TestToolModel_GetAll(t *testing.T) {
 		Sort:         "id",
 	}
 
-	tools, _, err := testQueries.Tools.GetAll(f, s)
+	tools, _, err := testQueries.Tools.GetAll(f, s, "", false)
 	require.NoError(t, err)
 	require.Len(t, tools, 10)
 	require.NotEmpty(t, tools)
@@ -101,8 +123,145 @@ func TestToolModel_GetAllPublished(t *testing.T) {
 		Sort:         "id",
 	}
 
-	tools, _, err := testQueries.Tools.GetAllPublished(s, f)
+	tools, _, err := testQueries.Tools.GetAllPublished(s, "", f)
 	require.NoError(t, err)
 	require.Len(t, tools, 10)
 	require.NotEmpty(t, tools)
 }
+
+func TestSlugify(t *testing.T) {
+	require.Equal(t, "next-js", slugify("Next.js"))
+	require.Equal(t, "react-hook-form", slugify("  React Hook  Form "))
+	require.Equal(t, "tool", slugify("---"))
+}
+
+func TestSlugMatchesName(t *testing.T) {
+	require.True(t, slugMatchesName("prisma", "Prisma"))
+	require.True(t, slugMatchesName("prisma-2", "Prisma"))
+	require.False(t, slugMatchesName("prisma-orm", "Prisma"))
+	require.False(t, slugMatchesName("drizzle", "Prisma"))
+}
+
+func TestToolModel_GetBySlug(t *testing.T) {
+	tool := CreateTool(t)
+	require.NotEmpty(t, tool.Slug)
+
+	dbTool, err := testQueries.Tools.GetBySlug(tool.Slug)
+	require.NoError(t, err)
+	require.Equal(t, tool.ID, dbTool.ID)
+}
+
+func TestToolModel_Insert_DuplicateName(t *testing.T) {
+	tool := CreateTool(t)
+
+	duplicate := &Tool{
+		Name:        tool.Name,
+		Category:    tool.Category,
+		Description: tool.Description,
+	}
+	err := testQueries.Tools.Insert(duplicate)
+	require.NoError(t, err)
+	require.NotEqual(t, tool.Slug, duplicate.Slug)
+	require.True(t, slugMatchesName(duplicate.Slug, duplicate.Name))
+}
+
+func TestToolModel_Update_SlugRedirect(t *testing.T) {
+	tool := CreateTool(t)
+	oldSlug := tool.Slug
+
+	tool.Name = random.RandString(10)
+	err := testQueries.Tools.Update(&tool, 0)
+	require.NoError(t, err)
+	require.NotEqual(t, oldSlug, tool.Slug)
+
+	current, err := testQueries.Tools.GetRedirectSlug(oldSlug)
+	require.NoError(t, err)
+	require.Equal(t, tool.Slug, current)
+}
+
+func TestToolModel_Update_Conflict(t *testing.T) {
+	tool := CreateTool(t)
+	stale := tool
+
+	tool.Description = "first edit"
+	err := testQueries.Tools.Update(&tool, 0)
+	require.NoError(t, err)
+	require.Equal(t, stale.Version+1, tool.Version)
+
+	stale.Description = "second edit"
+	err = testQueries.Tools.Update(&stale, 0)
+	require.ErrorIs(t, err, ErrEditConflict)
+}
+
+func TestToolModel_Delete_NotFound(t *testing.T) {
+	err := testQueries.Tools.Delete(0)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestToolModel_Restore(t *testing.T) {
+	tool := CreateTool(t)
+
+	err := testQueries.Tools.Delete(tool.ID)
+	require.NoError(t, err)
+
+	deleted, err := testQueries.Tools.GetDeleted()
+	require.NoError(t, err)
+	require.Contains(t, toolIDs(deleted), tool.ID)
+
+	err = testQueries.Tools.Restore(tool.ID)
+	require.NoError(t, err)
+
+	dbTool, err := testQueries.Tools.Get(tool.ID)
+	require.NoError(t, err)
+	require.Equal(t, tool.ID, dbTool.ID)
+
+	err = testQueries.Tools.Restore(tool.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestToolModel_PurgeDeleted(t *testing.T) {
+	tool := CreateTool(t)
+
+	err := testQueries.Tools.Delete(tool.ID)
+	require.NoError(t, err)
+
+	_, err = testQueries.Tools.PurgeDeleted(-time.Minute)
+	require.NoError(t, err)
+
+	err = testQueries.Tools.Restore(tool.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func toolIDs(tools []*Tool) []int64 {
+	ids := make([]int64, 0, len(tools))
+	for _, tool := range tools {
+		ids = append(ids, tool.ID)
+	}
+	return ids
+}
+
+func TestToolModel_GetAll_CategorySubtree(t *testing.T) {
+	parent := CreateCategory(t)
+	child := CreateCategory(t)
+	child.ParentID = parent.ID
+	err := testQueries.Categories.Update(&child, 0)
+	require.NoError(t, err)
+
+	tool := CreateTool(t)
+	tool.Category = child.Name
+	err = testQueries.Tools.Update(&tool, 0)
+	require.NoError(t, err)
+	other := CreateTool(t)
+
+	f := Filters{
+		Page:         1,
+		PageSize:     20,
+		SortSafelist: []string{"id"},
+		Sort:         "id",
+	}
+
+	tools, _, err := testQueries.Tools.GetAll(f, "", parent.Name, false)
+	require.NoError(t, err)
+	require.Contains(t, toolIDs(tools), tool.ID)
+	require.NotContains(t, toolIDs(tools), other.ID)
+}
@@ -0,0 +1,347 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	validator "github.com/wdt/internal/validators"
+)
+
+const (
+	UploadPending     = "pending"
+	UploadComplete    = "complete"
+	UploadFailed      = "failed"
+	UploadQuarantined = "quarantined"
+)
+
+// Upload tracks a file a user stored, from the moment a presigned URL is
+// handed out until it has been verified and processed into image variants.
+// Key is the raw object the client uploads to. StoredBytes is what the
+// upload currently occupies in storage. Threat is what the malware scanner
+// found in a quarantined upload.
+type Upload struct {
+	ID          string        `json:"id"`
+	UserID      int64         `json:"userId"`
+	Key         string        `json:"-"`
+	ContentType string        `json:"contentType"`
+	Size        int64         `json:"size"`
+	StoredBytes int64         `json:"storedBytes"`
+	Status      string        `json:"status"`
+	Threat      string        `json:"threat,omitempty"`
+	Images      ImageVariants `json:"images"`
+	CreatedAt   time.Time     `json:"createdAt"`
+	ExpiresAt   time.Time     `json:"expiresAt"`
+	CompletedAt *time.Time    `json:"completedAt,omitempty"`
+}
+
+func ValidateUpload(v *validator.Validator, upload *Upload, maxSize int64) {
+	v.Check(validator.PermittedValue(upload.ContentType, "image/png", "image/jpeg", "image/gif", "image/webp"), "contentType", "must be image/png, image/jpeg, image/gif or image/webp")
+	v.Check(upload.Size > 0, "size", "must be greater than zero")
+	v.Check(upload.Size <= maxSize, "size", "must not be larger than the upload limit")
+}
+
+type UploadModel struct {
+	DB *sql.DB
+}
+
+func (m UploadModel) Insert(upload *Upload) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return insertUpload(ctx, m.DB, upload)
+}
+
+func insertUpload(ctx context.Context, q querier, upload *Upload) error {
+	query := `INSERT INTO uploads (id, user_id, key, content_type, size, stored_bytes, status, images, expires_at, completed_at, orphaned_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, CASE WHEN $7 = 'complete' THEN NOW() END, CASE WHEN $7 = 'complete' THEN NOW() END)
+			  RETURNING created_at, completed_at`
+
+	args := []interface{}{
+		upload.ID,
+		upload.UserID,
+		upload.Key,
+		upload.ContentType,
+		upload.Size,
+		upload.StoredBytes,
+		upload.Status,
+		upload.Images,
+		upload.ExpiresAt,
+	}
+
+	return q.QueryRowContext(ctx, query, args...).Scan(&upload.CreatedAt, &upload.CompletedAt)
+}
+
+// Get returns the upload with the given id if it belongs to userID.
+func (m UploadModel) Get(id string, userID int64) (*Upload, error) {
+	query := `SELECT id, user_id, key, content_type, size, stored_bytes, status, threat, images, created_at, expires_at, completed_at
+			  FROM uploads
+			  WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	upload, err := scanUpload(m.DB.QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return upload, nil
+}
+
+func scanUpload(row interface{ Scan(dest ...any) error }) (*Upload, error) {
+	var upload Upload
+	err := row.Scan(
+		&upload.ID,
+		&upload.UserID,
+		&upload.Key,
+		&upload.ContentType,
+		&upload.Size,
+		&upload.StoredBytes,
+		&upload.Status,
+		&upload.Threat,
+		&upload.Images,
+		&upload.CreatedAt,
+		&upload.ExpiresAt,
+		&upload.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// Complete marks a pending upload as verified and stores its image variants.
+// It returns ErrEditConflict when the upload is no longer pending. The
+// upload counts as orphaned until a tool references it.
+func (m UploadModel) Complete(upload *Upload) error {
+	query := `UPDATE uploads
+			  SET status = 'complete', images = $1, stored_bytes = $2, completed_at = NOW(), orphaned_at = NOW()
+			  WHERE id = $3 AND status = 'pending'
+			  RETURNING status, completed_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, upload.Images, upload.StoredBytes, upload.ID).Scan(&upload.Status, &upload.CompletedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Fail marks a pending upload as rejected.
+func (m UploadModel) Fail(upload *Upload) error {
+	query := `UPDATE uploads
+			  SET status = 'failed'
+			  WHERE id = $1 AND status = 'pending'`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, upload.ID)
+	if err != nil {
+		return err
+	}
+
+	upload.Status = UploadFailed
+	return nil
+}
+
+// Quarantine marks a pending upload as flagged by the malware scanner and
+// records where its file was moved.
+func (m UploadModel) Quarantine(upload *Upload, key, threat string) error {
+	query := `UPDATE uploads
+			  SET status = 'quarantined', key = $1, threat = $2, stored_bytes = size
+			  WHERE id = $3 AND status = 'pending'`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, key, threat, upload.ID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrEditConflict
+	}
+
+	upload.Status = UploadQuarantined
+	upload.Key = key
+	upload.Threat = threat
+	return nil
+}
+
+// uploadInUse matches uploads that a tool links to or that any revision
+// snapshot still shows, since rolling back to that revision would bring the
+// image back.
+const uploadInUse = `(EXISTS (SELECT 1 FROM tool_uploads r WHERE r.upload_id = u.id)
+			  OR EXISTS (SELECT 1 FROM jsonb_array_elements(u.images) uv, tool_revisions tr
+						 WHERE tr.snapshot->'images' @> jsonb_build_array(jsonb_build_object('url', uv->>'url'))))`
+
+// GetOrphaned returns up to limit uploads that can be deleted: pending or
+// failed uploads whose URL expired more than grace ago, and completed
+// uploads that no tool or revision has referenced for longer than grace.
+// Quarantined uploads are kept for review.
+func (m UploadModel) GetOrphaned(grace time.Duration, limit int) ([]*Upload, error) {
+	query := `SELECT id, user_id, key, content_type, size, stored_bytes, status, threat, images, created_at, expires_at, completed_at
+			  FROM uploads u
+			  WHERE (u.status IN ('pending', 'failed') AND u.expires_at < NOW() - make_interval(secs => $1))
+			  OR (u.status = 'complete' AND coalesce(u.orphaned_at, u.completed_at) < NOW() - make_interval(secs => $1)
+				  AND NOT ` + uploadInUse + `)
+			  ORDER BY u.created_at
+			  LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, grace.Seconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uploads []*Upload
+	for rows.Next() {
+		upload, err := scanUpload(rows)
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, upload)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return uploads, nil
+}
+
+// DeleteOrphaned removes the record of an upload unless a tool or revision
+// started referencing it in the meantime, and reports whether it did.
+func (m UploadModel) DeleteOrphaned(id string) (bool, error) {
+	query := `DELETE FROM uploads u
+			  WHERE u.id = $1 AND NOT ` + uploadInUse
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows > 0, nil
+}
+
+// linkToolUploads records which completed uploads the tool's images point
+// at, replacing its previous links. Uploads the tool stopped using start
+// counting as orphaned.
+func linkToolUploads(ctx context.Context, q querier, tool *Tool) error {
+	urls := []string{}
+	for _, variant := range tool.Images {
+		urls = append(urls, variant.URL)
+	}
+
+	queries := []string{
+		`WITH unlinked AS (
+			DELETE FROM tool_uploads WHERE tool_id = $1 RETURNING upload_id
+		 )
+		 UPDATE uploads SET orphaned_at = NOW()
+		 WHERE id IN (SELECT upload_id FROM unlinked)`,
+		`INSERT INTO tool_uploads (tool_id, upload_id)
+		 SELECT DISTINCT $1::bigint, u.id
+		 FROM uploads u, unnest($2::text[]) AS url
+		 WHERE u.status = 'complete' AND u.images @> jsonb_build_array(jsonb_build_object('url', url))`,
+		`UPDATE uploads u SET orphaned_at = NULL
+		 WHERE EXISTS (SELECT 1 FROM tool_uploads r WHERE r.upload_id = u.id AND r.tool_id = $1)`,
+	}
+
+	args := [][]any{
+		{tool.ID},
+		{tool.ID, pq.Array(urls)},
+		{tool.ID},
+	}
+
+	for i, query := range queries {
+		_, err := q.ExecContext(ctx, query, args[i]...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StorageUsage sums the uploads of one user. Orphaned counts the uploads no
+// tool references.
+type StorageUsage struct {
+	UserID        int64  `json:"userId"`
+	Email         string `json:"email"`
+	Name          string `json:"name"`
+	Uploads       int64  `json:"uploads"`
+	Bytes         int64  `json:"bytes"`
+	Orphaned      int64  `json:"orphaned"`
+	OrphanedBytes int64  `json:"orphanedBytes"`
+}
+
+// UsageByOwner returns the limit users that occupy the most storage.
+func (m UploadModel) UsageByOwner(limit int) ([]StorageUsage, error) {
+	query := `SELECT u.user_id, us.email, coalesce(us.name, ''),
+				  count(*), coalesce(sum(u.stored_bytes), 0),
+				  count(*) FILTER (WHERE NOT ` + uploadInUse + `),
+				  coalesce(sum(u.stored_bytes) FILTER (WHERE NOT ` + uploadInUse + `), 0)
+			  FROM uploads u
+			  JOIN users us ON us.id = u.user_id
+			  GROUP BY u.user_id, us.email, us.name
+			  ORDER BY 5 DESC, u.user_id
+			  LIMIT $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := []StorageUsage{}
+	for rows.Next() {
+		var u StorageUsage
+		err := rows.Scan(&u.UserID, &u.Email, &u.Name, &u.Uploads, &u.Bytes, &u.Orphaned, &u.OrphanedBytes)
+		if err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return usage, nil
+}
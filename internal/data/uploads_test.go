@@ -0,0 +1,164 @@
+package data
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func CreatePendingUpload(t *testing.T, userID int64) *Upload {
+	id := uuid.NewString()
+	upload := &Upload{
+		ID:          id,
+		UserID:      userID,
+		Key:         "incoming/" + id + ".png",
+		ContentType: "image/png",
+		Size:        1024,
+		Status:      UploadPending,
+		ExpiresAt:   time.Now().Add(15 * time.Minute),
+	}
+
+	err := testQueries.Uploads.Insert(upload)
+	require.NoError(t, err)
+	require.NotZero(t, upload.CreatedAt)
+	require.Nil(t, upload.CompletedAt)
+
+	return upload
+}
+
+func TestUploadModel_Complete(t *testing.T) {
+	user := CreateRandomUser(t)
+	upload := CreatePendingUpload(t, user.ID)
+
+	_, err := testQueries.Uploads.Get(upload.ID, user.ID+1)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	upload.Images = ImageVariants{{Name: "original", Format: "png", Width: 10, Height: 10, URL: "https://cdn.example.com/original.png"}}
+	err = testQueries.Uploads.Complete(upload)
+	require.NoError(t, err)
+	require.Equal(t, UploadComplete, upload.Status)
+	require.NotNil(t, upload.CompletedAt)
+
+	err = testQueries.Uploads.Complete(upload)
+	require.ErrorIs(t, err, ErrEditConflict)
+
+	dbUpload, err := testQueries.Uploads.Get(upload.ID, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, UploadComplete, dbUpload.Status)
+	require.Equal(t, upload.Images, dbUpload.Images)
+}
+
+func TestUploadModel_Fail(t *testing.T) {
+	user := CreateRandomUser(t)
+	upload := CreatePendingUpload(t, user.ID)
+
+	err := testQueries.Uploads.Fail(upload)
+	require.NoError(t, err)
+
+	err = testQueries.Uploads.Complete(upload)
+	require.ErrorIs(t, err, ErrEditConflict)
+
+	dbUpload, err := testQueries.Uploads.Get(upload.ID, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, UploadFailed, dbUpload.Status)
+}
+
+func orphanedIDs(t *testing.T) map[string]bool {
+	uploads, err := testQueries.Uploads.GetOrphaned(-time.Hour, 10000)
+	require.NoError(t, err)
+
+	ids := map[string]bool{}
+	for _, upload := range uploads {
+		ids[upload.ID] = true
+	}
+	return ids
+}
+
+func TestUploadModel_ToolLinks(t *testing.T) {
+	user := CreateRandomUser(t)
+	upload := CreatePendingUpload(t, user.ID)
+	upload.Images = ImageVariants{{Name: "original", Format: "png", Width: 10, Height: 10, URL: "https://cdn.example.com/" + upload.ID + "/original.png"}}
+	upload.StoredBytes = 2048
+	err := testQueries.Uploads.Complete(upload)
+	require.NoError(t, err)
+
+	require.True(t, orphanedIDs(t)[upload.ID])
+
+	tool := CreateTool(t)
+	tool.Images = upload.Images
+	err = testQueries.Tools.Update(&tool, 0)
+	require.NoError(t, err)
+	require.False(t, orphanedIDs(t)[upload.ID])
+
+	usage, err := testQueries.Uploads.UsageByOwner(10000)
+	require.NoError(t, err)
+	var found bool
+	for _, u := range usage {
+		if u.UserID == user.ID {
+			found = true
+			require.Equal(t, int64(1), u.Uploads)
+			require.Equal(t, int64(2048), u.Bytes)
+			require.Equal(t, int64(0), u.Orphaned)
+		}
+	}
+	require.True(t, found)
+
+	// A revision still shows the image, so a rollback could bring it back.
+	tool.Images = nil
+	err = testQueries.Tools.Update(&tool, 0)
+	require.NoError(t, err)
+	require.False(t, orphanedIDs(t)[upload.ID])
+
+	ok, err := testQueries.Uploads.DeleteOrphaned(upload.ID)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, err = testQueries.Tools.DB.Exec(`DELETE FROM tools WHERE id = $1`, tool.ID)
+	require.NoError(t, err)
+	require.True(t, orphanedIDs(t)[upload.ID])
+
+	ok, err = testQueries.Uploads.DeleteOrphaned(upload.ID)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, err = testQueries.Uploads.Get(upload.ID, user.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestUploadModel_DeleteOrphaned_KeepsReferenced(t *testing.T) {
+	user := CreateRandomUser(t)
+	upload := CreatePendingUpload(t, user.ID)
+	upload.Images = ImageVariants{{Name: "original", Format: "png", Width: 10, Height: 10, URL: "https://cdn.example.com/" + upload.ID + "/original.png"}}
+	err := testQueries.Uploads.Complete(upload)
+	require.NoError(t, err)
+
+	tool := &Tool{Name: "linked", Category: "linked", Images: upload.Images}
+	err = testQueries.Tools.Insert(tool)
+	require.NoError(t, err)
+
+	ok, err := testQueries.Uploads.DeleteOrphaned(upload.ID)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestUploadModel_Quarantine(t *testing.T) {
+	user := CreateRandomUser(t)
+	upload := CreatePendingUpload(t, user.ID)
+
+	err := testQueries.Uploads.Quarantine(upload, "quarantine/"+upload.ID, "Eicar-Test-Signature")
+	require.NoError(t, err)
+	require.Equal(t, UploadQuarantined, upload.Status)
+
+	err = testQueries.Uploads.Quarantine(upload, "quarantine/"+upload.ID, "Eicar-Test-Signature")
+	require.ErrorIs(t, err, ErrEditConflict)
+
+	dbUpload, err := testQueries.Uploads.Get(upload.ID, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, UploadQuarantined, dbUpload.Status)
+	require.Equal(t, "quarantine/"+upload.ID, dbUpload.Key)
+	require.Equal(t, "Eicar-Test-Signature", dbUpload.Threat)
+	require.Equal(t, upload.Size, dbUpload.StoredBytes)
+
+	require.False(t, orphanedIDs(t)[upload.ID])
+}
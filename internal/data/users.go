@@ -7,6 +7,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/lib/pq"
 	validator "github.com/wdt/internal/validators"
 )
 
@@ -26,21 +27,40 @@ type User struct {
 	CreatedAt time.Time `json:"createdAt,omitempty"`
 	Version   int64     `json:"version,omitempty"`
 	Role      string    `json:"role"`
+	Language  string    `json:"language,omitempty"`
+	Digest    bool      `json:"digest"`
+	// DigestCategories limits the digest to these categories and their
+	// subcategories. When empty the categories of the user's favorites are
+	// used instead.
+	DigestCategories []string `json:"digestCategories,omitempty"`
 }
 
 func (u *User) IsAnonymous() bool {
 	return u == AnonymousUser
 }
 
+func (u *User) IsAdmin() bool {
+	return u.Role == "admin"
+}
+
 func ValidateEmail(v *validator.Validator, email string) {
 	v.Check(email != "", "email", "Email must be provided.")
 	v.Check(validator.Matches(email, validator.EmailRX), "email", "must be a valid email address.")
 }
 
+func ValidateDigestCategories(v *validator.Validator, categories []string) {
+	v.Check(len(categories) <= 20, "digestCategories", "must not contain more than 20 categories")
+	v.Check(validator.Unique(categories), "digestCategories", "must not contain duplicate values")
+	for _, category := range categories {
+		v.Check(category != "", "digestCategories", "must not contain empty names")
+		v.Check(len(category) <= 40, "digestCategories", "must not contain names longer than 40 bytes")
+	}
+}
+
 
 func (m UserModel) Insert(user *User) error {
-	query := `INSERT INTO users (name, email, image_url)
-			 VALUES ($1, $2 ,$3)
+	query := `INSERT INTO users (name, email, image_url, language)
+			 VALUES ($1, $2 ,$3, $4)
 			 RETURNING id, created_at, version
 			`
 
@@ -48,6 +68,7 @@ func (m UserModel) Insert(user *User) error {
 		user.Name,
 		user.Email,
 		NewNullString(user.ImageUrl),
+		user.Language,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -71,7 +92,7 @@ func (m UserModel) Insert(user *User) error {
 }
 
 func (m UserModel) Get(id int64, email string) (*User, error) {
-	query := `SELECT id, created_at, COALESCE(name, ''), email, COALESCE(image_url, ''), version, role
+	query := `SELECT id, created_at, COALESCE(name, ''), email, COALESCE(image_url, ''), version, role, language, digest, digest_categories
 			 FROM users
 			 WHERE id = $1 OR email = $2`
 
@@ -87,6 +108,9 @@ func (m UserModel) Get(id int64, email string) (*User, error) {
 		&user.ImageUrl,
 		&user.Version,
 		&user.Role,
+		&user.Language,
+		&user.Digest,
+		pq.Array(&user.DigestCategories),
 	)
 	if err != nil {
 		switch {
@@ -103,7 +127,7 @@ func (m UserModel) Get(id int64, email string) (*User, error) {
 func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error) {
 	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
 	query := `
-        SELECT u.id, u.created_at, COALESCE(u.name, ''), u.email ,COALESCE(u.image_url,''), u.version, u.role
+        SELECT u.id, u.created_at, COALESCE(u.name, ''), u.email ,COALESCE(u.image_url,''), u.version, u.role, u.language, u.digest, u.digest_categories
         FROM users u
         INNER JOIN tokens t
         ON u.id = t.user_id
@@ -126,6 +150,9 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 		&user.ImageUrl,
 		&user.Version,
 		&user.Role,
+		&user.Language,
+		&user.Digest,
+		pq.Array(&user.DigestCategories),
 	)
 	if err != nil {
 		switch {
@@ -137,4 +164,30 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 	}
 
 	return &user, nil
-}
\ No newline at end of file
+}
+
+// UpdatePreferences saves the user's settings. Opting into the digest
+// starts its period, so the first digest lists the tools published since.
+// It returns ErrEditConflict when the user was changed since it was read.
+func (m UserModel) UpdatePreferences(user *User) error {
+	query := `UPDATE users
+			  SET language = $1, digest = $2, last_digest_at = CASE WHEN $2 AND NOT digest THEN NOW() ELSE last_digest_at END,
+			      digest_categories = coalesce($3::text[], '{}'), version = version + 1
+			  WHERE id = $4 AND version = $5
+			  RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, user.Language, user.Digest, pq.Array(user.DigestCategories), user.ID, user.Version).Scan(&user.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
@@ -77,3 +77,31 @@ func TestUserModel_GetForToken_NotFound(t *testing.T) {
 	require.Error(t, err)
 	require.ErrorIs(t, err, ErrRecordNotFound)
 }
+
+func TestUserModel_UpdatePreferences(t *testing.T) {
+	user := CreateRandomUser(t)
+	version := user.Version
+
+	user.Language = "de"
+	err := testQueries.Users.UpdatePreferences(&user)
+	require.NoError(t, err)
+	require.Equal(t, version+1, user.Version)
+
+	dbUser, err := testQueries.Users.Get(user.ID, "")
+	require.NoError(t, err)
+	require.Equal(t, "de", dbUser.Language)
+	require.Empty(t, dbUser.DigestCategories)
+
+	dbUser.DigestCategories = []string{"DB", "UI"}
+	err = testQueries.Users.UpdatePreferences(dbUser)
+	require.NoError(t, err)
+
+	dbUser, err = testQueries.Users.Get(user.ID, "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"DB", "UI"}, dbUser.DigestCategories)
+
+	stale := user
+	stale.Version = version
+	err = testQueries.Users.UpdatePreferences(&stale)
+	require.ErrorIs(t, err, ErrEditConflict)
+}
@@ -0,0 +1,97 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// WebsiteHealth is the latest result of checking a tool's website.
+// FailingSince is set while the site keeps failing, and Flagged once it has
+// been failing for longer than the configured number of days.
+type WebsiteHealth struct {
+	CheckedAt    time.Time  `json:"checkedAt"`
+	OK           bool       `json:"ok"`
+	StatusCode   int        `json:"statusCode,omitempty"`
+	LatencyMs    int64      `json:"latencyMs"`
+	RedirectURL  string     `json:"redirectUrl,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	FailingSince *time.Time `json:"failingSince,omitempty"`
+	Flagged      bool       `json:"flagged"`
+}
+
+// WebsiteCheckTarget is a tool whose website is due for a check.
+type WebsiteCheckTarget struct {
+	ToolID  int64
+	Website string
+}
+
+type WebsiteCheckModel struct {
+	DB *sql.DB
+}
+
+// GetDue returns up to limit tools whose website was never checked or was
+// last checked more than interval ago, least recently checked first.
+func (m WebsiteCheckModel) GetDue(interval time.Duration, limit int) ([]WebsiteCheckTarget, error) {
+	query := `SELECT t.id, t.website
+			  FROM tools t
+			  LEFT JOIN website_checks w ON w.tool_id = t.id
+			  WHERE t.deleted_at IS NULL
+			  AND (w.checked_at IS NULL OR w.checked_at < NOW() - make_interval(secs => $1))
+			  ORDER BY w.checked_at NULLS FIRST, t.id
+			  LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, interval.Seconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var targets []WebsiteCheckTarget
+	for rows.Next() {
+		var target WebsiteCheckTarget
+		err := rows.Scan(&target.ToolID, &target.Website)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return targets, nil
+}
+
+// Record stores the result of a check. A tool is flagged when its website
+// has been failing for at least flagAfter, and unflagged by the next
+// successful check.
+func (m WebsiteCheckModel) Record(toolID int64, health *WebsiteHealth, flagAfter time.Duration) error {
+	query := `INSERT INTO website_checks (tool_id, ok, status_code, latency_ms, redirect_url, error, failing_since, flagged)
+			  VALUES ($1, $2, $3, $4, $5, $6, CASE WHEN $2 THEN NULL ELSE NOW() END, NOT $2 AND $7::float8 <= 0)
+			  ON CONFLICT (tool_id) DO UPDATE
+			  SET checked_at = NOW(), ok = EXCLUDED.ok, status_code = EXCLUDED.status_code, latency_ms = EXCLUDED.latency_ms,
+				  redirect_url = EXCLUDED.redirect_url, error = EXCLUDED.error,
+				  failing_since = CASE WHEN EXCLUDED.ok THEN NULL ELSE coalesce(website_checks.failing_since, NOW()) END,
+				  flagged = NOT EXCLUDED.ok AND coalesce(website_checks.failing_since, NOW()) <= NOW() - make_interval(secs => $7)
+			  RETURNING checked_at, failing_since, flagged`
+
+	args := []interface{}{
+		toolID,
+		health.OK,
+		health.StatusCode,
+		health.LatencyMs,
+		health.RedirectURL,
+		health.Error,
+		flagAfter.Seconds(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&health.CheckedAt, &health.FailingSince, &health.Flagged)
+}
@@ -0,0 +1,35 @@
+package data
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestWebsiteCheckModel_Record(t *testing.T) {
+	tool := CreateTool(t)
+
+	due, err := testQueries.WebsiteChecks.GetDue(time.Hour, 1000)
+	require.NoError(t, err)
+	require.Contains(t, due, WebsiteCheckTarget{ToolID: tool.ID, Website: tool.Website})
+
+	failing := &WebsiteHealth{StatusCode: 500, Error: "unexpected status 500"}
+	err = testQueries.WebsiteChecks.Record(tool.ID, failing, time.Hour)
+	require.NoError(t, err)
+	require.NotNil(t, failing.FailingSince)
+	require.False(t, failing.Flagged)
+
+	err = testQueries.WebsiteChecks.Record(tool.ID, failing, 0)
+	require.NoError(t, err)
+	require.True(t, failing.Flagged)
+
+	healthy := &WebsiteHealth{OK: true, StatusCode: 200}
+	err = testQueries.WebsiteChecks.Record(tool.ID, healthy, 0)
+	require.NoError(t, err)
+	require.Nil(t, healthy.FailingSince)
+	require.False(t, healthy.Flagged)
+
+	due, err = testQueries.WebsiteChecks.GetDue(time.Hour, 1000)
+	require.NoError(t, err)
+	require.NotContains(t, due, WebsiteCheckTarget{ToolID: tool.ID, Website: tool.Website})
+}
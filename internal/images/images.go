@@ -0,0 +1,149 @@
+// Package images turns uploaded images into the set of variants served to
+// clients.
+package images
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"strconv"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrTooLarge          = errors.New("image dimensions are too large")
+)
+
+// ThumbnailSizes are the bounding boxes, in pixels, of the generated
+// thumbnails.
+var ThumbnailSizes = []int{64, 128, 512}
+
+// maxPixels bounds the decoded size so a small, highly compressed file
+// cannot exhaust memory. A decoded image takes at most 8 bytes per pixel, so
+// this keeps a single upload around 32 MB; logos do not need more.
+const maxPixels = 4_000_000
+
+const Original = "original"
+
+// Variant is one encoded rendition of an uploaded image. Name is Original or
+// the thumbnail size.
+type Variant struct {
+	Name        string
+	Format      string
+	ContentType string
+	Width       int
+	Height      int
+	Data        []byte
+}
+
+// Extension is the file extension matching the variant's format.
+func (v Variant) Extension() string {
+	return Extension(v.Format)
+}
+
+// Extension is the file extension used for format.
+func Extension(format string) string {
+	if format == "jpeg" {
+		return "jpg"
+	}
+	return format
+}
+
+// Process sniffs data, rejects anything that is not a PNG, JPEG, GIF or WebP
+// image and returns the re-encoded original plus every thumbnail. Decoding
+// and re-encoding drops all metadata such as EXIF. Photos stay JPEG; everything else becomes PNG so
+// transparency survives. Thumbnails never upscale.
+func Process(data []byte) ([]Variant, error) {
+	var format string
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		format = "jpeg"
+	case "image/png", "image/gif", "image/webp":
+		format = "png"
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	if config.Width < 1 || config.Height < 1 || config.Width*config.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+
+	original, err := encode(Original, format, src)
+	if err != nil {
+		return nil, err
+	}
+	variants := []Variant{original}
+
+	for _, size := range ThumbnailSizes {
+		v, err := encode(sizeName(size), format, thumbnail(src, size))
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+
+	return variants, nil
+}
+
+func encode(name, format string, img image.Image) (Variant, error) {
+	var buf bytes.Buffer
+	var err error
+
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+	default:
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return Variant{}, err
+	}
+
+	return Variant{
+		Name:        name,
+		Format:      format,
+		ContentType: "image/" + format,
+		Width:       img.Bounds().Dx(),
+		Height:      img.Bounds().Dy(),
+		Data:        buf.Bytes(),
+	}, nil
+}
+
+// thumbnail scales img to fit within size x size, keeping its aspect ratio.
+func thumbnail(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+
+	if w >= h {
+		w, h = size, max(1, h*size/w)
+	} else {
+		w, h = max(1, w*size/h), size
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+func sizeName(size int) string {
+	return strconv.Itoa(size)
+}
@@ -0,0 +1,100 @@
+package images
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+func TestProcess_PNG(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, testImage(1000, 250)))
+
+	variants, err := Process(buf.Bytes())
+	require.NoError(t, err)
+	require.Len(t, variants, 1+len(ThumbnailSizes))
+
+	sizes := map[string][2]int{}
+	for _, v := range variants {
+		require.Equal(t, "png", v.Format)
+		require.NotEmpty(t, v.Data)
+
+		img, _, err := image.Decode(bytes.NewReader(v.Data))
+		require.NoError(t, err)
+		require.Equal(t, v.Width, img.Bounds().Dx())
+		require.Equal(t, v.Height, img.Bounds().Dy())
+		sizes[v.Name] = [2]int{v.Width, v.Height}
+	}
+
+	require.Equal(t, [2]int{1000, 250}, sizes[Original])
+	require.Equal(t, [2]int{64, 16}, sizes["64"])
+	require.Equal(t, [2]int{128, 32}, sizes["128"])
+	require.Equal(t, [2]int{512, 128}, sizes["512"])
+}
+
+func TestProcess_NoUpscale(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, testImage(100, 40)))
+
+	variants, err := Process(buf.Bytes())
+	require.NoError(t, err)
+	for _, v := range variants {
+		if v.Name == "512" || v.Name == "128" {
+			require.Equal(t, 100, v.Width)
+			require.Equal(t, 40, v.Height)
+		}
+	}
+}
+
+func TestProcess_StripsEXIF(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, testImage(32, 32), nil))
+
+	exif := append([]byte("Exif\x00\x00"), []byte("GPS secret location")...)
+	segment := []byte{0xff, 0xe1, byte((len(exif) + 2) >> 8), byte(len(exif) + 2)}
+	data := append([]byte{0xff, 0xd8}, segment...)
+	data = append(data, exif...)
+	data = append(data, buf.Bytes()[2:]...)
+
+	variants, err := Process(data)
+	require.NoError(t, err)
+	for _, v := range variants {
+		if v.Name == Original {
+			require.Equal(t, "jpeg", v.Format)
+		}
+		require.NotContains(t, string(v.Data), "GPS secret location")
+	}
+}
+
+func TestProcess_RejectsLargeImages(t *testing.T) {
+	for _, size := range [][2]int{{maxPixels + 1, 1}, {1, maxPixels + 1}, {2100, 2100}} {
+		var buf bytes.Buffer
+		require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, size[0], size[1]))))
+
+		_, err := Process(buf.Bytes())
+		require.ErrorIs(t, err, ErrTooLarge)
+	}
+}
+
+func TestProcess_RejectsNonImages(t *testing.T) {
+	_, err := Process([]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"))
+	require.ErrorIs(t, err, ErrUnsupportedFormat)
+
+	_, err = Process([]byte("\x89PNG\r\n\x1a\nnot really a png"))
+	require.ErrorIs(t, err, ErrUnsupportedFormat)
+}
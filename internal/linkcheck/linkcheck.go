@@ -0,0 +1,210 @@
+// Package linkcheck checks whether websites still respond.
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	neturl "net/url"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrInvalidURL     = errors.New("not an absolute http or https URL")
+	ErrPrivateAddress = errors.New("refusing to connect to a non-public address")
+)
+
+// Result is the outcome of checking one URL. RedirectURL is set when the
+// final response came from a different URL than the one checked.
+type Result struct {
+	URL         string
+	StatusCode  int
+	Latency     time.Duration
+	RedirectURL string
+	Attempts    int
+	Err         error
+}
+
+// OK reports whether the site answered with a success status.
+func (r Result) OK() bool {
+	return r.Err == nil && r.StatusCode >= 200 && r.StatusCode < 400
+}
+
+// Checker requests websites with HEAD, falling back to GET for servers that
+// refuse HEAD. Network errors, 429s and 5xx responses are retried with
+// exponential backoff.
+type Checker struct {
+	Client      *http.Client
+	Concurrency int
+	Retries     int
+	Backoff     time.Duration
+	UserAgent   string
+}
+
+// New returns a Checker whose client only connects to public addresses.
+// Websites are user supplied, so without the guard the checker could be
+// pointed at the server's own network or a cloud metadata endpoint.
+func New(concurrency int) *Checker {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: publicOnly,
+	}
+	transport := &http.Transport{
+		// No proxy, so every connection goes through the guarded dialer.
+		Proxy:               nil,
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+		MaxIdleConns:        concurrency,
+		IdleConnTimeout:     30 * time.Second,
+	}
+
+	return &Checker{
+		Client:      &http.Client{Timeout: 10 * time.Second, Transport: transport},
+		Concurrency: concurrency,
+		Retries:     2,
+		Backoff:     time.Second,
+		UserAgent:   "web-dev-tools-linkcheck/1.0",
+	}
+}
+
+// CheckAll checks every URL with at most Concurrency requests in flight and
+// returns the results in the same order as urls.
+func (c *Checker) CheckAll(ctx context.Context, urls []string) []Result {
+	results := make([]Result, len(urls))
+
+	concurrency := c.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+				results[i] = c.Check(ctx, u)
+			case <-ctx.Done():
+				results[i] = Result{URL: u, Err: ctx.Err()}
+			}
+		}(i, u)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// Check requests a single URL, retrying transient failures.
+func (c *Checker) Check(ctx context.Context, url string) Result {
+	u, err := neturl.Parse(url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Result{URL: url, Err: ErrInvalidURL}
+	}
+
+	var result Result
+	for attempt := 0; attempt <= c.Retries; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(c.Backoff << (attempt - 1))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				result.Err = ctx.Err()
+				return result
+			case <-timer.C:
+			}
+		}
+
+		result = c.try(ctx, url)
+		result.Attempts = attempt + 1
+		if !retryable(result) {
+			break
+		}
+	}
+
+	return result
+}
+
+func (c *Checker) try(ctx context.Context, url string) Result {
+	result := c.request(ctx, http.MethodHead, url)
+	if result.Err == nil && result.StatusCode < 400 {
+		return result
+	}
+	if ctx.Err() != nil {
+		return result
+	}
+	return c.request(ctx, http.MethodGet, url)
+}
+
+func (c *Checker) request(ctx context.Context, method, url string) Result {
+	result := Result{URL: url}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	req.Header.Set("User-Agent", c.UserAgent)
+
+	start := time.Now()
+	resp, err := c.Client.Do(req)
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	result.StatusCode = resp.StatusCode
+	if final := resp.Request.URL.String(); final != url {
+		result.RedirectURL = final
+	}
+	if result.StatusCode >= 400 {
+		result.Err = fmt.Errorf("unexpected status %d", result.StatusCode)
+	}
+
+	return result
+}
+
+func retryable(r Result) bool {
+	if r.StatusCode == 0 {
+		return r.Err != nil && !errors.Is(r.Err, context.Canceled) && !errors.Is(r.Err, ErrInvalidURL) && !errors.Is(r.Err, ErrPrivateAddress)
+	}
+	return r.StatusCode == http.StatusTooManyRequests || r.StatusCode >= 500
+}
+
+// publicOnly is a net.Dialer Control function that refuses connections to
+// loopback, private, link-local and other non-public addresses. It runs
+// after name resolution for every connection, redirects included, so a
+// hostname cannot be made to resolve to an internal address.
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !isPublic(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, which IsPrivate does
+// not cover.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() &&
+		!addr.IsPrivate() &&
+		!sharedAddressSpace.Contains(addr)
+}
@@ -0,0 +1,167 @@
+package linkcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestChecker returns a Checker that may connect to the loopback test
+// servers.
+func newTestChecker(concurrency int) *Checker {
+	c := New(concurrency)
+	c.Client = &http.Client{Timeout: 10 * time.Second}
+	c.Backoff = time.Millisecond
+	return c
+}
+
+func TestCheck_OK(t *testing.T) {
+	methods := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods <- r.Method
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	result := newTestChecker(1).Check(context.Background(), srv.URL)
+	require.Equal(t, http.MethodHead, <-methods)
+	require.True(t, result.OK())
+	require.Equal(t, http.StatusOK, result.StatusCode)
+	require.Equal(t, 1, result.Attempts)
+	require.Empty(t, result.RedirectURL)
+}
+
+func TestCheck_FallsBackToGet(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	result := newTestChecker(1).Check(context.Background(), srv.URL)
+	require.True(t, result.OK())
+}
+
+func TestCheck_Redirect(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	result := newTestChecker(1).Check(context.Background(), srv.URL+"/old")
+	require.True(t, result.OK())
+	require.Equal(t, srv.URL+"/new", result.RedirectURL)
+}
+
+func TestCheck_RetriesServerErrors(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// HEAD and the GET fallback of the first attempt both fail.
+		if requests.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	result := newTestChecker(1).Check(context.Background(), srv.URL)
+	require.True(t, result.OK())
+	require.Equal(t, 2, result.Attempts)
+}
+
+func TestCheck_NotFound(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	result := newTestChecker(1).Check(context.Background(), srv.URL)
+	require.False(t, result.OK())
+	require.Equal(t, http.StatusNotFound, result.StatusCode)
+	require.Equal(t, 1, result.Attempts)
+}
+
+func TestCheck_InvalidURL(t *testing.T) {
+	result := newTestChecker(1).Check(context.Background(), "javascript:alert(1)")
+	require.ErrorIs(t, result.Err, ErrInvalidURL)
+	require.False(t, result.OK())
+}
+
+func TestCheck_RefusesPrivateAddresses(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer srv.Close()
+
+	c := New(1)
+	c.Backoff = time.Millisecond
+
+	result := c.Check(context.Background(), srv.URL)
+	require.ErrorIs(t, result.Err, ErrPrivateAddress)
+	require.Equal(t, 1, result.Attempts)
+	require.Zero(t, hits.Load())
+}
+
+func TestIsPublic(t *testing.T) {
+	for addr, public := range map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"fc00::1":          false,
+		"fe80::1":          false,
+		"::ffff:127.0.0.1": false,
+		"224.0.0.1":        false,
+	} {
+		require.Equal(t, public, isPublic(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestCheckAll_BoundsConcurrency(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	urls := make([]string, 10)
+	for i := range urls {
+		urls[i] = srv.URL
+	}
+
+	results := newTestChecker(3).CheckAll(context.Background(), urls)
+	require.Len(t, results, 10)
+	for _, result := range results {
+		require.True(t, result.OK())
+	}
+	require.LessOrEqual(t, maxInFlight.Load(), int32(3))
+}
@@ -0,0 +1,188 @@
+// Package mailer sends email through Resend, an SMTP server or, for
+// development and tests, by writing .eml files to a directory.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoRecipients  = errors.New("mailer: message has no recipients")
+	ErrInvalidHeader = errors.New("mailer: invalid header")
+)
+
+// Message is one email. HTML, Text or both may be set. Headers holds extra
+// headers such as List-Unsubscribe.
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	HTML    string
+	Text    string
+	Headers map[string]string
+}
+
+type Sender interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Bytes renders msg as an RFC 5322 message with a multipart/alternative
+// body when it has both a text and an HTML part.
+func (msg *Message) Bytes() ([]byte, error) {
+	if len(msg.To) == 0 {
+		return nil, ErrNoRecipients
+	}
+
+	// Addresses and extra headers are written verbatim, so a line break in
+	// one of them could inject headers or a body of its own.
+	err := checkHeaders(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := messageID(msg.From)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+
+	header("From", msg.From)
+	header("To", strings.Join(msg.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", id)
+	header("MIME-Version", "1.0")
+
+	keys := make([]string, 0, len(msg.Headers))
+	for key := range msg.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		header(textproto.CanonicalMIMEHeaderKey(key), msg.Headers[key])
+	}
+
+	switch {
+	case msg.HTML != "" && msg.Text != "":
+		w := multipart.NewWriter(&buf)
+		header("Content-Type", "multipart/alternative; boundary="+w.Boundary())
+		buf.WriteString("\r\n")
+
+		for _, part := range []struct{ contentType, body string }{
+			{"text/plain; charset=utf-8", msg.Text},
+			{"text/html; charset=utf-8", msg.HTML},
+		} {
+			pw, err := w.CreatePart(textproto.MIMEHeader{
+				"Content-Type":              {part.contentType},
+				"Content-Transfer-Encoding": {"quoted-printable"},
+			})
+			if err != nil {
+				return nil, err
+			}
+			err = writeQuotedPrintable(pw, part.body)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err = w.Close()
+		if err != nil {
+			return nil, err
+		}
+	default:
+		contentType, body := "text/plain; charset=utf-8", msg.Text
+		if msg.HTML != "" {
+			contentType, body = "text/html; charset=utf-8", msg.HTML
+		}
+		header("Content-Type", contentType)
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+
+		err = writeQuotedPrintable(&buf, body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func checkHeaders(msg *Message) error {
+	values := append([]string{msg.From}, msg.To...)
+	for key, value := range msg.Headers {
+		if key == "" || strings.ContainsAny(key, ": \t\r\n") {
+			return fmt.Errorf("%w: name %q", ErrInvalidHeader, key)
+		}
+		values = append(values, value)
+	}
+
+	for _, value := range values {
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("%w: %q contains a line break", ErrInvalidHeader, value)
+		}
+	}
+	return nil
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	_, err := qp.Write([]byte(body))
+	if err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func messageID(from string) (string, error) {
+	domain := "localhost"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if _, d, ok := strings.Cut(addr.Address, "@"); ok {
+			domain = d
+		}
+	}
+
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">", nil
+}
+
+// addresses returns the bare addresses of the sender and recipients, as SMTP
+// expects them in MAIL FROM and RCPT TO.
+func addresses(msg *Message) (string, []string, error) {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return "", nil, fmt.Errorf("mailer: invalid from address: %w", err)
+	}
+
+	to := make([]string, len(msg.To))
+	for i, recipient := range msg.To {
+		addr, err := mail.ParseAddress(recipient)
+		if err != nil {
+			return "", nil, fmt.Errorf("mailer: invalid recipient: %w", err)
+		}
+		to[i] = addr.Address
+	}
+
+	return from.Address, to, nil
+}
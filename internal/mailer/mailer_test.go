@@ -0,0 +1,217 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testMessage() *Message {
+	return &Message{
+		From:    "Web Dev Tools <info@web-dev-tools.xyz>",
+		To:      []string{"someone@example.com"},
+		Subject: "Grüße",
+		HTML:    "<p>Hello <b>there</b></p>",
+		Text:    "Hello there",
+		Headers: map[string]string{"list-unsubscribe": "<https://example.com/unsubscribe>"},
+	}
+}
+
+// parts decodes the text and HTML bodies of a rendered message.
+func parts(t *testing.T, m *mail.Message) map[string]string {
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative", mediaType)
+
+	bodies := map[string]string{}
+	r := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		p, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		contentType, _, err := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		body, err := io.ReadAll(quotedprintable.NewReader(p))
+		require.NoError(t, err)
+		bodies[contentType] = string(body)
+	}
+	return bodies
+}
+
+func TestMessage_Bytes(t *testing.T) {
+	raw, err := testMessage().Bytes()
+	require.NoError(t, err)
+
+	m, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	require.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	require.NoError(t, err)
+	require.Equal(t, "Grüße", subject)
+	require.Equal(t, "someone@example.com", m.Header.Get("To"))
+	require.Equal(t, "<https://example.com/unsubscribe>", m.Header.Get("List-Unsubscribe"))
+	require.True(t, strings.HasSuffix(m.Header.Get("Message-Id"), "@web-dev-tools.xyz>"))
+
+	bodies := parts(t, m)
+	require.Equal(t, "Hello there", bodies["text/plain"])
+	require.Equal(t, "<p>Hello <b>there</b></p>", bodies["text/html"])
+
+	_, err = (&Message{From: "a@example.com"}).Bytes()
+	require.ErrorIs(t, err, ErrNoRecipients)
+}
+
+func TestMessage_Bytes_RejectsLineBreaks(t *testing.T) {
+	for _, edit := range []func(m *Message){
+		func(m *Message) { m.To = []string{"someone@example.com\r\nBcc: victim@example.com"} },
+		func(m *Message) { m.From = "info@web-dev-tools.xyz\nBcc: victim@example.com" },
+		func(m *Message) { m.Headers["List-Unsubscribe"] = "<https://example.com>\r\n\r\nbody" },
+		func(m *Message) { m.Headers["X-Evil: yes\r\nBcc"] = "victim@example.com" },
+	} {
+		msg := testMessage()
+		edit(msg)
+
+		_, err := msg.Bytes()
+		require.ErrorIs(t, err, ErrInvalidHeader)
+	}
+}
+
+func TestFileDrop(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	f, err := NewFileDrop(dir)
+	require.NoError(t, err)
+
+	err = f.Send(context.Background(), testMessage())
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	raw, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	m, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	require.NoError(t, err)
+	require.Equal(t, "Hello there", parts(t, m)["text/plain"])
+}
+
+// fakeSMTP accepts a single session and sends the received envelope and data
+// on the returned channel. With a TLS config it offers STARTTLS.
+func fakeSMTP(t *testing.T, config *tls.Config) (string, int, <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 fake ESMTP")
+
+		var envelope []string
+		secure := false
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+			switch command {
+			case "EHLO":
+				if config != nil && !secure {
+					tp.PrintfLine("250-fake")
+					tp.PrintfLine("250 STARTTLS")
+				} else {
+					tp.PrintfLine("250 fake")
+				}
+			case "STARTTLS":
+				tp.PrintfLine("220 ready")
+				tlsConn := tls.Server(conn, config)
+				if tlsConn.Handshake() != nil {
+					return
+				}
+				conn = tlsConn
+				tp = textproto.NewConn(conn)
+				secure = true
+			case "MAIL", "RCPT":
+				envelope = append(envelope, line)
+				tp.PrintfLine("250 ok")
+			case "DATA":
+				tp.PrintfLine("354 go ahead")
+				data, err := io.ReadAll(tp.DotReader())
+				if err != nil {
+					return
+				}
+				tp.PrintfLine("250 queued")
+				received <- strconv.FormatBool(secure) + "\n" + strings.Join(envelope, "\n") + "\n" + string(data)
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				return
+			default:
+				tp.PrintfLine("250 ok")
+			}
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, received
+}
+
+func TestSMTP_Send(t *testing.T) {
+	host, port, received := fakeSMTP(t, nil)
+
+	err := NewSMTP(host, port, "", "", false).Send(context.Background(), testMessage())
+	require.NoError(t, err)
+
+	session := <-received
+	require.True(t, strings.HasPrefix(session, "false\nMAIL FROM:<info@web-dev-tools.xyz>"), session)
+	require.Contains(t, session, "RCPT TO:<someone@example.com>")
+	require.Contains(t, session, "Subject: =?utf-8?q?Gr=C3=BC=C3=9Fe?=")
+}
+
+func TestSMTP_StartTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(nil)
+	defer srv.Close()
+
+	host, port, received := fakeSMTP(t, srv.TLS)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	s := NewSMTP(host, port, "", "", true)
+	s.tlsConfig = &tls.Config{RootCAs: pool, ServerName: "example.com"}
+
+	err := s.Send(context.Background(), testMessage())
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(<-received, "true\n"))
+}
+
+func TestSMTP_StartTLSRequired(t *testing.T) {
+	host, port, _ := fakeSMTP(t, nil)
+
+	err := NewSMTP(host, port, "", "", true).Send(context.Background(), testMessage())
+	require.ErrorIs(t, err, ErrStartTLSUnsupported)
+}
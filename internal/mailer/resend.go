@@ -1,15 +1,42 @@
 package mailer
 
-import "github.com/resendlabs/resend-go"
+import (
+	"context"
+	"net/http"
+	"time"
 
-type Mailer struct {
-	ApiKey string
+	"github.com/resendlabs/resend-go"
+)
+
+// Resend sends email through the Resend API.
+type Resend struct {
+	client *resend.Client
 }
 
-func NewMailer(apiKey string) Mailer {
-	return Mailer{ApiKey: apiKey}
+func NewResend(apiKey string) *Resend {
+	return &Resend{
+		client: resend.NewCustomClient(&http.Client{Timeout: 10 * time.Second}, apiKey),
+	}
 }
 
-func (m *Mailer) MailClient() *resend.Client {
-	return resend.NewClient(m.ApiKey)
-}
\ No newline at end of file
+func (r *Resend) Send(ctx context.Context, msg *Message) error {
+	if len(msg.To) == 0 {
+		return ErrNoRecipients
+	}
+	err := checkHeaders(msg)
+	if err != nil {
+		return err
+	}
+
+	params := &resend.SendEmailRequest{
+		From:    msg.From,
+		To:      msg.To,
+		Subject: msg.Subject,
+		Html:    msg.HTML,
+		Text:    msg.Text,
+		Headers: msg.Headers,
+	}
+
+	_, err = r.client.Emails.Send(params)
+	return err
+}
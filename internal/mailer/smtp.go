@@ -0,0 +1,117 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+var ErrStartTLSUnsupported = errors.New("mailer: smtp server does not support STARTTLS")
+
+// SMTP sends email through an SMTP server. With StartTLS set the connection
+// is upgraded before authenticating and a server that cannot do so is an
+// error rather than a reason to send in plain text.
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	StartTLS bool
+	Timeout  time.Duration
+
+	tlsConfig *tls.Config
+}
+
+func NewSMTP(host string, port int, username, password string, startTLS bool) *SMTP {
+	return &SMTP{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: password,
+		StartTLS: startTLS,
+		Timeout:  10 * time.Second,
+	}
+}
+
+func (s *SMTP) Send(ctx context.Context, msg *Message) error {
+	body, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	from, to, err := addresses(msg)
+	if err != nil {
+		return err
+	}
+
+	d := net.Dialer{Timeout: s.Timeout}
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(s.Host, strconv.Itoa(s.Port)))
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(s.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if s.StartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return ErrStartTLSUnsupported
+		}
+
+		config := s.tlsConfig
+		if config == nil {
+			config = &tls.Config{ServerName: s.Host}
+		}
+		err = c.StartTLS(config)
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.Username != "" {
+		err = c.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.Mail(from)
+	if err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		err = c.Rcpt(recipient)
+		if err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+)
+
+var ErrSuppressed = errors.New("mailer: recipient is suppressed")
+
+// SuppressionList reports whether an address must not receive email, for
+// example because mail to it bounced permanently.
+type SuppressionList interface {
+	IsSuppressed(email string) (bool, error)
+}
+
+// Suppressing refuses to send to suppressed recipients and hands everything
+// else to Sender.
+type Suppressing struct {
+	Sender Sender
+	List   SuppressionList
+}
+
+func (s Suppressing) Send(ctx context.Context, msg *Message) error {
+	for _, recipient := range msg.To {
+		address := recipient
+		if addr, err := mail.ParseAddress(recipient); err == nil {
+			address = addr.Address
+		}
+
+		suppressed, err := s.List.IsSuppressed(address)
+		if err != nil {
+			return err
+		}
+		if suppressed {
+			return fmt.Errorf("%w: %s", ErrSuppressed, address)
+		}
+	}
+
+	return s.Sender.Send(ctx, msg)
+}
@@ -0,0 +1,65 @@
+package mailer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebhookTolerance is how far the timestamp of a webhook may be from now
+// before it is rejected as a replay.
+const WebhookTolerance = 5 * time.Minute
+
+var (
+	ErrInvalidSignature = errors.New("mailer: invalid webhook signature")
+	ErrStaleWebhook     = errors.New("mailer: webhook timestamp outside of tolerance")
+)
+
+// VerifyWebhook checks the Svix-style signature Resend puts on its webhooks.
+// The secret is the whsec_ value from the provider's dashboard. The
+// svix-signature header may hold several space-separated signatures, for
+// example while the secret is rotated, and one matching is enough.
+func VerifyWebhook(secret string, header http.Header, body []byte, now time.Time) error {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, "whsec_"))
+	if err != nil || len(key) == 0 {
+		return ErrInvalidSignature
+	}
+
+	id := header.Get("svix-id")
+	timestamp := header.Get("svix-timestamp")
+	if id == "" || timestamp == "" {
+		return ErrInvalidSignature
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	sent := time.Unix(seconds, 0)
+	if now.Sub(sent) > WebhookTolerance || sent.Sub(now) > WebhookTolerance {
+		return ErrStaleWebhook
+	}
+
+	expected := SignWebhook(key, id, timestamp, body)
+	for _, signature := range strings.Fields(header.Get("svix-signature")) {
+		version, sig, ok := strings.Cut(signature, ",")
+		if ok && version == "v1" && hmac.Equal([]byte(sig), []byte(expected)) {
+			return nil
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+// SignWebhook returns the base64 v1 signature of a webhook.
+func SignWebhook(key []byte, id, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "." + timestamp + "."))
+	mac.Write(body)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
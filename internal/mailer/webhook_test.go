@@ -0,0 +1,69 @@
+package mailer
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyWebhook(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	secret := "whsec_" + base64.StdEncoding.EncodeToString(key)
+	body := []byte(`{"type":"email.bounced"}`)
+	now := time.Now()
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	header := func(timestamp, signature string) http.Header {
+		h := http.Header{}
+		h.Set("svix-id", "msg_1")
+		h.Set("svix-timestamp", timestamp)
+		h.Set("svix-signature", signature)
+		return h
+	}
+	valid := "v1," + SignWebhook(key, "msg_1", timestamp, body)
+
+	require.NoError(t, VerifyWebhook(secret, header(timestamp, valid), body, now))
+	require.NoError(t, VerifyWebhook(secret, header(timestamp, "v1,b2xk "+valid), body, now))
+
+	err := VerifyWebhook(secret, header(timestamp, valid), []byte(`{"type":"email.delivered"}`), now)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	err = VerifyWebhook(secret, header(timestamp, "v2,"+SignWebhook(key, "msg_1", timestamp, body)), body, now)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	err = VerifyWebhook(secret, header(timestamp, valid), body, now.Add(10*time.Minute))
+	require.ErrorIs(t, err, ErrStaleWebhook)
+
+	err = VerifyWebhook("", header(timestamp, valid), body, now)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	err = VerifyWebhook(secret, http.Header{}, body, now)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+type suppressionList map[string]bool
+
+func (l suppressionList) IsSuppressed(email string) (bool, error) {
+	return l[email], nil
+}
+
+func TestSuppressing(t *testing.T) {
+	dir := t.TempDir()
+	drop, err := NewFileDrop(dir)
+	require.NoError(t, err)
+
+	s := Suppressing{Sender: drop, List: suppressionList{"bounced@example.com": true}}
+
+	msg := testMessage()
+	msg.To = []string{"Someone <bounced@example.com>"}
+	err = s.Send(context.Background(), msg)
+	require.ErrorIs(t, err, ErrSuppressed)
+
+	err = s.Send(context.Background(), testMessage())
+	require.NoError(t, err)
+}
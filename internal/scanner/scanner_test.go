@@ -0,0 +1,121 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// fakeClamd speaks enough of the clamd protocol to answer INSTREAM: it
+// reassembles the chunks and reports the EICAR test string as infected.
+// Every received stream is sent on the returned channel.
+func fakeClamd(t *testing.T, maxStream int) (string, <-chan []byte) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	streams := make(chan []byte, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveClamd(conn, maxStream, streams)
+		}
+	}()
+
+	return ln.Addr().String(), streams
+}
+
+func serveClamd(conn net.Conn, maxStream int, streams chan<- []byte) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	command, err := r.ReadString(0)
+	if err != nil || command != "zINSTREAM\x00" {
+		conn.Write([]byte("UNKNOWN COMMAND\x00"))
+		return
+	}
+
+	var stream bytes.Buffer
+	for {
+		var size uint32
+		err := binary.Read(r, binary.BigEndian, &size)
+		if err != nil {
+			return
+		}
+		if size == 0 {
+			break
+		}
+		if stream.Len()+int(size) > maxStream {
+			conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+			return
+		}
+		_, err = io.CopyN(&stream, r, int64(size))
+		if err != nil {
+			return
+		}
+	}
+
+	streams <- stream.Bytes()
+	if bytes.Contains(stream.Bytes(), []byte(eicar)) {
+		conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+		return
+	}
+	conn.Write([]byte("stream: OK\x00"))
+}
+
+func TestClamd_Clean(t *testing.T) {
+	addr, streams := fakeClamd(t, 1<<20)
+	c := NewClamd(addr)
+	c.ChunkSize = 7
+
+	content := strings.Repeat("harmless image bytes ", 100)
+	result, err := c.Scan(context.Background(), strings.NewReader(content))
+	require.NoError(t, err)
+	require.True(t, result.Clean)
+	require.Empty(t, result.Threat)
+	require.Equal(t, content, string(<-streams))
+}
+
+func TestClamd_Infected(t *testing.T) {
+	addr, _ := fakeClamd(t, 1<<20)
+
+	result, err := NewClamd(addr).Scan(context.Background(), strings.NewReader("prefix "+eicar))
+	require.NoError(t, err)
+	require.False(t, result.Clean)
+	require.Equal(t, "Eicar-Test-Signature", result.Threat)
+}
+
+func TestClamd_Error(t *testing.T) {
+	addr, _ := fakeClamd(t, 10)
+
+	_, err := NewClamd(addr).Scan(context.Background(), strings.NewReader(strings.Repeat("x", 100)))
+	require.Error(t, err)
+}
+
+func TestClamd_Unreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+
+	_, err = NewClamd(addr).Scan(context.Background(), strings.NewReader("x"))
+	require.Error(t, err)
+}
+
+func TestNoop(t *testing.T) {
+	result, err := Noop{}.Scan(context.Background(), strings.NewReader(eicar))
+	require.NoError(t, err)
+	require.True(t, result.Clean)
+}
@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Local stores files below Dir and serves them over HTTP, so uploads work
+// without any cloud account. BaseURL is where its handler is mounted.
+// Presigned URLs carry an expiry and an HMAC of the key; they only survive a
+// restart when a signing key is configured.
+type Local struct {
+	Dir     string
+	BaseURL string
+	key     []byte
+}
+
+func NewLocal(dir, baseURL, signingKey string) (*Local, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	key := []byte(signingKey)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		_, err = rand.Read(key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Local{Dir: dir, BaseURL: strings.TrimSuffix(baseURL, "/"), key: key}, nil
+}
+
+func (l *Local) path(key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(l.Dir, filepath.FromSlash(key)), nil
+}
+
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(name), 0o755)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see a partial file.
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, r)
+	if err == nil && size >= 0 && n != size {
+		err = io.ErrUnexpectedEOF
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (l *Local) PublicURL(key string) string {
+	return l.BaseURL + "/" + key
+}
+
+func (l *Local) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return l.presign(http.MethodGet, key, "", "", expiry)
+}
+
+func (l *Local) PresignPut(ctx context.Context, key, contentType string, size int64, expiry time.Duration) (string, error) {
+	return l.presign(http.MethodPut, key, contentType, strconv.FormatInt(size, 10), expiry)
+}
+
+func (l *Local) presign(method, key, contentType, size string, expiry time.Duration) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(expiry).Unix(), 10)
+	q := url.Values{}
+	q.Set("expires", expires)
+	q.Set("signature", l.sign(method, key, expires, contentType, size))
+
+	return l.PublicURL(key) + "?" + q.Encode(), nil
+}
+
+func (l *Local) sign(method, key, expires, contentType, size string) string {
+	mac := hmac.New(sha256.New, l.key)
+	mac.Write([]byte(strings.Join([]string{method, key, expires, contentType, size}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks the expiry and signature of a presigned URL against the
+// request. GETs without a signature are public URLs and pass unless the key
+// is private.
+func (l *Local) verify(r *http.Request, key string) bool {
+	q := r.URL.Query()
+	signature := q.Get("signature")
+	if signature == "" {
+		return r.Method != http.MethodPut && !Private(key)
+	}
+
+	expires, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+
+	method, contentType, size := http.MethodGet, "", ""
+	if r.Method == http.MethodPut {
+		method, contentType, size = http.MethodPut, r.Header.Get("Content-Type"), strconv.FormatInt(r.ContentLength, 10)
+	}
+	return hmac.Equal([]byte(signature), []byte(l.sign(method, key, q.Get("expires"), contentType, size)))
+}
+
+func (l *Local) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	name, err := l.path(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return ObjectInfo{}, ErrNotFound
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return ObjectInfo{}, ErrNotFound
+	}
+
+	// The key's extension is chosen by the uploader, so the content type is
+	// sniffed from the stored bytes instead.
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return ObjectInfo{}, err
+	}
+	return ObjectInfo{Size: info.Size(), ContentType: http.DetectContentType(head[:n])}, nil
+}
+
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	name, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// ServeHTTP serves the file named by the request path, which must already
+// have the mount prefix stripped, and accepts uploads to presigned PUT URLs.
+func (l *Local) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodPut {
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	name, err := l.path(key)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !l.verify(r, key) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		err = l.Put(r.Context(), key, http.MaxBytesReader(w, r.Body, r.ContentLength), r.ContentLength, r.Header.Get("Content-Type"))
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
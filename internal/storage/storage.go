@@ -0,0 +1,74 @@
+// Package storage stores uploaded files in an S3-compatible bucket or on the
+// local disk.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidKey = errors.New("invalid storage key")
+	ErrNotFound   = errors.New("object not found")
+)
+
+// IncomingPrefix holds raw uploads until they are scanned and processed.
+// Only the re-encoded variants stored under ImagesPrefix are ever public.
+const IncomingPrefix = "incoming/"
+
+// QuarantinePrefix holds files that failed a malware scan.
+const QuarantinePrefix = "quarantine/"
+
+// ImagesPrefix holds the processed image variants, the only public files.
+const ImagesPrefix = "images/"
+
+// Private reports whether key lies under a prefix that must never be served
+// without a presigned URL.
+func Private(key string) bool {
+	return strings.HasPrefix(key, IncomingPrefix) || strings.HasPrefix(key, QuarantinePrefix)
+}
+
+// ObjectInfo describes a stored object.
+type ObjectInfo struct {
+	Size        int64
+	ContentType string
+}
+
+// Storage is where uploaded files live. Keys are slash separated paths such
+// as "images/<id>/original.png".
+type Storage interface {
+	// Put stores size bytes read from r under key.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// PublicURL is the permanent URL of key.
+	PublicURL(key string) string
+	// PresignGet returns a URL that grants read access to key until expiry
+	// has passed.
+	PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error)
+	// PresignPut returns a URL that lets a client upload key directly until
+	// expiry has passed. The upload must send exactly the given Content-Type
+	// and Content-Length headers.
+	PresignPut(ctx context.Context, key, contentType string, size int64, expiry time.Duration) (string, error)
+	// Stat describes key, or returns ErrNotFound.
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	// Get opens key for reading, or returns ErrNotFound.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// cleanKey rejects keys that are empty, absolute or that would escape the
+// storage root.
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned != key || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,249 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanKey(t *testing.T) {
+	for _, key := range []string{"images/a/original.png", "a.txt"} {
+		cleaned, err := cleanKey(key)
+		require.NoError(t, err)
+		require.Equal(t, key, cleaned)
+	}
+
+	for _, key := range []string{"", "/etc/passwd", "../secret", "images/../../secret", "images//a", ".", "a\\b"} {
+		_, err := cleanKey(key)
+		require.ErrorIs(t, err, ErrInvalidKey, key)
+	}
+}
+
+func newTestLocal(t *testing.T) (*Local, *httptest.Server) {
+	srv := httptest.NewUnstartedServer(nil)
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	l, err := NewLocal(t.TempDir(), srv.URL+"/files/", "secret")
+	require.NoError(t, err)
+	srv.Config.Handler = http.StripPrefix("/files", l)
+	return l, srv
+}
+
+func get(t *testing.T, url string) (int, string) {
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+func TestLocal_PutServeDelete(t *testing.T) {
+	l, srv := newTestLocal(t)
+	ctx := context.Background()
+
+	err := l.Put(ctx, "images/a/original.txt", strings.NewReader("hello"), 5, "text/plain")
+	require.NoError(t, err)
+
+	url := l.PublicURL("images/a/original.txt")
+	require.Equal(t, srv.URL+"/files/images/a/original.txt", url)
+
+	status, body := get(t, url)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "hello", body)
+
+	err = l.Delete(ctx, "images/a/original.txt")
+	require.NoError(t, err)
+	err = l.Delete(ctx, "images/a/original.txt")
+	require.NoError(t, err)
+
+	status, _ = get(t, url)
+	require.Equal(t, http.StatusNotFound, status)
+}
+
+func TestLocal_PutRejectsShortReads(t *testing.T) {
+	l, _ := newTestLocal(t)
+
+	err := l.Put(context.Background(), "a.txt", strings.NewReader("hi"), 5, "text/plain")
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	status, _ := get(t, l.PublicURL("a.txt"))
+	require.Equal(t, http.StatusNotFound, status)
+}
+
+func TestLocal_RejectsTraversal(t *testing.T) {
+	l, srv := newTestLocal(t)
+
+	err := l.Put(context.Background(), "../escape.txt", strings.NewReader("x"), 1, "text/plain")
+	require.ErrorIs(t, err, ErrInvalidKey)
+
+	status, _ := get(t, srv.URL+"/files/%2e%2e/escape.txt")
+	require.Equal(t, http.StatusNotFound, status)
+
+	status, _ = get(t, srv.URL+"/files/")
+	require.Equal(t, http.StatusNotFound, status)
+}
+
+func TestLocal_PresignGet(t *testing.T) {
+	l, _ := newTestLocal(t)
+	ctx := context.Background()
+
+	err := l.Put(ctx, "a.txt", strings.NewReader("hello"), 5, "text/plain")
+	require.NoError(t, err)
+
+	url, err := l.PresignGet(ctx, "a.txt", time.Minute)
+	require.NoError(t, err)
+	status, body := get(t, url)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "hello", body)
+
+	status, _ = get(t, url[:len(url)-1]+"0")
+	require.Equal(t, http.StatusForbidden, status)
+
+	expired, err := l.PresignGet(ctx, "a.txt", -time.Minute)
+	require.NoError(t, err)
+	status, _ = get(t, expired)
+	require.Equal(t, http.StatusForbidden, status)
+}
+
+func TestS3_URLs(t *testing.T) {
+	cfg := S3Config{
+		Endpoint:  "s3.eu-central-1.amazonaws.com",
+		Bucket:    "bucket",
+		Region:    "eu-central-1",
+		AccessKey: "access",
+		SecretKey: "secret",
+		UseSSL:    true,
+	}
+
+	s, err := NewS3(cfg)
+	require.NoError(t, err)
+	require.Equal(t, "https://bucket.s3.eu-central-1.amazonaws.com/a/b.png", s.PublicURL("a/b.png"))
+
+	url, err := s.PresignGet(context.Background(), "a/b.png", time.Minute)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(url, "https://bucket.s3."), url)
+	require.Contains(t, url, "/a/b.png?")
+	require.Contains(t, url, "X-Amz-Signature=")
+
+	cfg.Endpoint = "localhost:9000"
+	cfg.UseSSL = false
+	cfg.PathStyle = true
+	s, err = NewS3(cfg)
+	require.NoError(t, err)
+	require.Equal(t, "http://localhost:9000/bucket/a/b.png", s.PublicURL("a/b.png"))
+
+	url, err = s.PresignGet(context.Background(), "a/b.png", time.Minute)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(url, "http://localhost:9000/bucket/a/b.png?"), url)
+
+	cfg.PublicURL = "https://cdn.example.com/"
+	s, err = NewS3(cfg)
+	require.NoError(t, err)
+	require.Equal(t, "https://cdn.example.com/a/b.png", s.PublicURL("a/b.png"))
+}
+
+func put(t *testing.T, url, contentType, body string) int {
+	req, err := http.NewRequest(http.MethodPut, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestLocal_PresignPut(t *testing.T) {
+	l, _ := newTestLocal(t)
+	ctx := context.Background()
+
+	url, err := l.PresignPut(ctx, IncomingPrefix+"a.png", "image/png", 5, time.Minute)
+	require.NoError(t, err)
+
+	require.Equal(t, http.StatusForbidden, put(t, url, "image/png", "toolong"))
+	require.Equal(t, http.StatusForbidden, put(t, url, "image/gif", "hello"))
+	require.Equal(t, http.StatusForbidden, put(t, l.PublicURL(IncomingPrefix+"a.png"), "image/png", "hello"))
+
+	_, err = l.Stat(ctx, IncomingPrefix+"a.png")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.Equal(t, http.StatusOK, put(t, url, "image/png", "hello"))
+
+	info, err := l.Stat(ctx, IncomingPrefix+"a.png")
+	require.NoError(t, err)
+	// The declared type is not trusted; "hello" is sniffed as text.
+	require.Equal(t, ObjectInfo{Size: 5, ContentType: "text/plain; charset=utf-8"}, info)
+
+	// Raw uploads are not public before they are scanned.
+	status, _ := get(t, l.PublicURL(IncomingPrefix+"a.png"))
+	require.Equal(t, http.StatusForbidden, status)
+
+	obj, err := l.Get(ctx, IncomingPrefix+"a.png")
+	require.NoError(t, err)
+	defer obj.Close()
+	body, err := io.ReadAll(obj)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(body))
+
+	// A presigned GET URL cannot be used to upload.
+	getURL, err := l.PresignGet(ctx, IncomingPrefix+"a.png", time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, put(t, getURL, "", ""))
+}
+
+func TestS3_PresignPut(t *testing.T) {
+	s, err := NewS3(S3Config{Endpoint: "localhost:9000", Bucket: "bucket", Region: "us-east-1", AccessKey: "a", SecretKey: "s", PathStyle: true})
+	require.NoError(t, err)
+
+	url, err := s.PresignPut(context.Background(), "uploads/a.png", "image/png", 5, time.Minute)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(url, "http://localhost:9000/bucket/uploads/a.png?"), url)
+	require.Contains(t, url, "X-Amz-SignedHeaders=content-length%3Bcontent-type%3Bhost")
+}
+
+func TestLocal_PrivatePrefixes(t *testing.T) {
+	l, _ := newTestLocal(t)
+	ctx := context.Background()
+
+	for _, prefix := range []string{IncomingPrefix, QuarantinePrefix} {
+		err := l.Put(ctx, prefix+"a.png", strings.NewReader("bad"), 3, "image/png")
+		require.NoError(t, err)
+
+		status, _ := get(t, l.PublicURL(prefix+"a.png"))
+		require.Equal(t, http.StatusForbidden, status)
+
+		url, err := l.PresignGet(ctx, prefix+"a.png", time.Minute)
+		require.NoError(t, err)
+		status, body := get(t, url)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "bad", body)
+	}
+}
+
+func TestLocal_StatSniffsContentType(t *testing.T) {
+	l, _ := newTestLocal(t)
+	ctx := context.Background()
+
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 16)
+	err := l.Put(ctx, "incoming/a.jpg", strings.NewReader(png), int64(len(png)), "image/jpeg")
+	require.NoError(t, err)
+
+	info, err := l.Stat(ctx, "incoming/a.jpg")
+	require.NoError(t, err)
+	require.Equal(t, int64(len(png)), info.Size)
+	require.Equal(t, "image/png", info.ContentType)
+
+	err = l.Put(ctx, "incoming/b.png", strings.NewReader("<html></html>"), 13, "image/png")
+	require.NoError(t, err)
+
+	info, err = l.Stat(ctx, "incoming/b.png")
+	require.NoError(t, err)
+	require.Equal(t, "text/html; charset=utf-8", info.ContentType)
+}
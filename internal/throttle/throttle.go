@@ -0,0 +1,78 @@
+// Package throttle limits how often an action may happen per key, such as
+// an email address or an IP, and tells callers how long to wait.
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// Throttle allows at most Limit actions per key within Window, and none
+// within Cooldown of the previous one. A zero Limit or Cooldown disables
+// that check. State is kept in memory.
+type Throttle struct {
+	Limit    int
+	Window   time.Duration
+	Cooldown time.Duration
+
+	mu   sync.Mutex
+	keys map[string][]time.Time
+}
+
+func New(limit int, window, cooldown time.Duration) *Throttle {
+	return &Throttle{
+		Limit:    limit,
+		Window:   window,
+		Cooldown: cooldown,
+		keys:     make(map[string][]time.Time),
+	}
+}
+
+// Allow records an action for key at now if it is allowed. Otherwise it
+// returns false and how long to wait until the action would be allowed.
+func (t *Throttle) Allow(key string, now time.Time) (bool, time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	recent := t.recent(key, now)
+
+	var wait time.Duration
+	if n := len(recent); n > 0 && t.Cooldown > 0 {
+		wait = max(wait, recent[n-1].Add(t.Cooldown).Sub(now))
+	}
+	if t.Limit > 0 && len(recent) >= t.Limit {
+		wait = max(wait, recent[len(recent)-t.Limit].Add(t.Window).Sub(now))
+	}
+	if wait > 0 {
+		t.keys[key] = recent
+		return false, wait
+	}
+
+	t.keys[key] = append(recent, now)
+	return true, 0
+}
+
+// Sweep forgets keys without actions in the window or cooldown so the
+// state does not grow without bound.
+func (t *Throttle) Sweep(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key := range t.keys {
+		if recent := t.recent(key, now); len(recent) > 0 {
+			t.keys[key] = recent
+		} else {
+			delete(t.keys, key)
+		}
+	}
+}
+
+// recent returns the actions for key that still count at now.
+func (t *Throttle) recent(key string, now time.Time) []time.Time {
+	keep := max(t.Window, t.Cooldown)
+	actions := t.keys[key]
+	for len(actions) > 0 && !actions[0].Add(keep).After(now) {
+		actions = actions[1:]
+	}
+	return actions
+}
@@ -0,0 +1,59 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottle_Cooldown(t *testing.T) {
+	th := New(0, 0, time.Minute)
+	now := time.Now()
+
+	ok, _ := th.Allow("a@example.com", now)
+	require.True(t, ok)
+
+	ok, wait := th.Allow("a@example.com", now.Add(20*time.Second))
+	require.False(t, ok)
+	require.Equal(t, 40*time.Second, wait)
+
+	ok, _ = th.Allow("b@example.com", now.Add(20*time.Second))
+	require.True(t, ok)
+
+	ok, _ = th.Allow("a@example.com", now.Add(time.Minute))
+	require.True(t, ok)
+}
+
+func TestThrottle_Limit(t *testing.T) {
+	th := New(3, time.Hour, 0)
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		ok, _ := th.Allow("10.0.0.1", now.Add(time.Duration(i)*time.Minute))
+		require.True(t, ok)
+	}
+
+	ok, wait := th.Allow("10.0.0.1", now.Add(10*time.Minute))
+	require.False(t, ok)
+	require.Equal(t, 50*time.Minute, wait)
+
+	// Refused attempts do not count.
+	ok, _ = th.Allow("10.0.0.1", now.Add(time.Hour))
+	require.True(t, ok)
+	ok, wait = th.Allow("10.0.0.1", now.Add(time.Hour))
+	require.False(t, ok)
+	require.Equal(t, time.Minute, wait)
+}
+
+func TestThrottle_Sweep(t *testing.T) {
+	th := New(5, time.Hour, time.Minute)
+	now := time.Now()
+
+	th.Allow("old", now)
+	th.Allow("new", now.Add(30*time.Minute))
+
+	th.Sweep(now.Add(time.Hour))
+	require.Len(t, th.keys, 1)
+	require.Contains(t, th.keys, "new")
+}
@@ -0,0 +1,19 @@
+package tokens
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+
+	"github.com/gorilla/securecookie"
+)
+
+// UseSecret derives the keys that sign and encrypt tokens from secret, so
+// tokens stay valid across restarts and between instances. Without it the
+// keys are random per process.
+func UseSecret(secret string) {
+	hashKey := sha512.Sum512([]byte("hash:" + secret))
+	blockKey := sha256.Sum256([]byte("block:" + secret))
+
+	cookieHandler = securecookie.New(hashKey[:], blockKey[:])
+	unsubscribeHandler = newUnsubscribeHandler(hashKey[:], blockKey[:])
+}
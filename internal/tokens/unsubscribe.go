@@ -0,0 +1,47 @@
+package tokens
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gorilla/securecookie"
+)
+
+// unsubscribeMaxAge is how long the unsubscribe link in an email keeps
+// working.
+const unsubscribeMaxAge = 365 * 24 * 60 * 60
+
+var ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
+
+var unsubscribeHandler = newUnsubscribeHandler(
+	securecookie.GenerateRandomKey(64),
+	securecookie.GenerateRandomKey(32),
+)
+
+func newUnsubscribeHandler(hashKey, blockKey []byte) *securecookie.SecureCookie {
+	return securecookie.New(hashKey, blockKey).MaxAge(unsubscribeMaxAge)
+}
+
+// CreateUnsubscribeToken returns a token that unsubscribes the user from
+// the given list, such as the weekly digest, without logging in.
+func CreateUnsubscribeToken(list string, userID int64) (string, error) {
+	return unsubscribeHandler.Encode("unsubscribe-"+list, strconv.FormatInt(userID, 10))
+}
+
+// ValidateUnsubscribeToken returns the id of the user a token for list was
+// created for.
+func ValidateUnsubscribeToken(list, token string) (int64, error) {
+	var value string
+
+	err := unsubscribeHandler.Decode("unsubscribe-"+list, token, &value)
+	if err != nil {
+		return 0, ErrInvalidUnsubscribeToken
+	}
+
+	userID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidUnsubscribeToken
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,43 @@
+package tokens
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnsubscribeToken(t *testing.T) {
+	token, err := CreateUnsubscribeToken("digest", 42)
+	require.NoError(t, err)
+
+	userID, err := ValidateUnsubscribeToken("digest", token)
+	require.NoError(t, err)
+	require.Equal(t, int64(42), userID)
+
+	_, err = ValidateUnsubscribeToken("other", token)
+	require.ErrorIs(t, err, ErrInvalidUnsubscribeToken)
+
+	_, err = ValidateUnsubscribeToken("digest", token+"x")
+	require.ErrorIs(t, err, ErrInvalidUnsubscribeToken)
+}
+
+func TestUseSecret(t *testing.T) {
+	UseSecret("secret")
+	token, err := CreateUnsubscribeToken("digest", 7)
+	require.NoError(t, err)
+	magic, err := CreateMagicLinkToken("someone@example.com")
+	require.NoError(t, err)
+
+	// A restarted process with the same secret accepts the tokens.
+	UseSecret("secret")
+	userID, err := ValidateUnsubscribeToken("digest", token)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), userID)
+	email, err := ValidateMagicLinkToken(magic)
+	require.NoError(t, err)
+	require.Equal(t, "someone@example.com", email)
+
+	UseSecret("other")
+	_, err = ValidateUnsubscribeToken("digest", token)
+	require.ErrorIs(t, err, ErrInvalidUnsubscribeToken)
+}
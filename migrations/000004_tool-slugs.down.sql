@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS tool_slug_redirects;
+DROP INDEX IF EXISTS tools_slug_idx;
+ALTER TABLE tools DROP COLUMN IF EXISTS slug;
@@ -0,0 +1,22 @@
+ALTER TABLE tools ADD COLUMN IF NOT EXISTS slug text;
+
+UPDATE tools SET slug = trim(both '-' FROM regexp_replace(lower(name), '[^a-z0-9]+', '-', 'g'));
+UPDATE tools SET slug = 'tool' WHERE slug = '';
+
+UPDATE tools t
+SET slug = t.slug || '-' || d.rn
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY slug ORDER BY id) AS rn
+    FROM tools
+) d
+WHERE t.id = d.id AND d.rn > 1;
+
+ALTER TABLE tools ALTER COLUMN slug SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS tools_slug_idx ON tools (slug);
+
+CREATE TABLE IF NOT EXISTS tool_slug_redirects (
+    slug text PRIMARY KEY,
+    tool_id bigint NOT NULL REFERENCES tools ON DELETE CASCADE,
+    created_at timestamp NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS tool_revisions (
+    id bigserial PRIMARY KEY,
+    tool_id bigint NOT NULL REFERENCES tools ON DELETE CASCADE,
+    version integer NOT NULL,
+    editor_id bigint REFERENCES users ON DELETE SET NULL,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    snapshot jsonb NOT NULL,
+    UNIQUE (tool_id, version)
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS tools_deleted_at_idx;
+DROP INDEX IF EXISTS categories_deleted_at_idx;
+ALTER TABLE tools DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE categories DROP COLUMN IF EXISTS deleted_at;
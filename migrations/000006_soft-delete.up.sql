@@ -0,0 +1,5 @@
+ALTER TABLE tools ADD COLUMN IF NOT EXISTS deleted_at timestamp;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS deleted_at timestamp;
+
+CREATE INDEX IF NOT EXISTS tools_deleted_at_idx ON tools (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS categories_deleted_at_idx ON categories (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS tools_publish_at_idx;
+DROP INDEX IF EXISTS tools_unpublish_at_idx;
+DROP INDEX IF EXISTS categories_publish_at_idx;
+DROP INDEX IF EXISTS categories_unpublish_at_idx;
+ALTER TABLE tools DROP COLUMN IF EXISTS publish_at;
+ALTER TABLE tools DROP COLUMN IF EXISTS unpublish_at;
+ALTER TABLE categories DROP COLUMN IF EXISTS publish_at;
+ALTER TABLE categories DROP COLUMN IF EXISTS unpublish_at;
@@ -0,0 +1,9 @@
+ALTER TABLE tools ADD COLUMN IF NOT EXISTS publish_at timestamptz;
+ALTER TABLE tools ADD COLUMN IF NOT EXISTS unpublish_at timestamptz;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS publish_at timestamptz;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS unpublish_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS tools_publish_at_idx ON tools (publish_at) WHERE publish_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS tools_unpublish_at_idx ON tools (unpublish_at) WHERE unpublish_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS categories_publish_at_idx ON categories (publish_at) WHERE publish_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS categories_unpublish_at_idx ON categories (unpublish_at) WHERE unpublish_at IS NOT NULL;
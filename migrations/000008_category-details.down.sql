@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS tools_category_idx;
+ALTER TABLE categories DROP COLUMN IF EXISTS description;
+ALTER TABLE categories DROP COLUMN IF EXISTS icon;
+ALTER TABLE categories DROP COLUMN IF EXISTS position;
@@ -0,0 +1,13 @@
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS description text NOT NULL DEFAULT '';
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS icon text NOT NULL DEFAULT '';
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS position integer NOT NULL DEFAULT 0;
+
+UPDATE categories c
+SET position = o.rn
+FROM (
+    SELECT id, row_number() OVER (ORDER BY name, id) AS rn
+    FROM categories
+) o
+WHERE c.id = o.id;
+
+CREATE INDEX IF NOT EXISTS tools_category_idx ON tools (category);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS favorites_tool_id_idx;
+DROP TABLE IF EXISTS tool_alternatives;
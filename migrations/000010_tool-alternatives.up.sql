@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS tool_alternatives (
+    tool_id bigint NOT NULL REFERENCES tools ON DELETE CASCADE,
+    alternative_id bigint NOT NULL REFERENCES tools ON DELETE CASCADE,
+    created_at timestamptz NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tool_id, alternative_id),
+    CHECK (tool_id < alternative_id)
+);
+
+CREATE INDEX IF NOT EXISTS tool_alternatives_alternative_id_idx ON tool_alternatives (alternative_id);
+CREATE INDEX IF NOT EXISTS favorites_tool_id_idx ON favorites (tool_id);
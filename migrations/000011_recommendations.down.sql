@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS favorites_user_id_idx;
+DROP TABLE IF EXISTS popular_tools;
+DROP TABLE IF EXISTS user_recommendations;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS user_recommendations (
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    tool_id bigint NOT NULL REFERENCES tools ON DELETE CASCADE,
+    rank integer NOT NULL,
+    score double precision NOT NULL,
+    reason text NOT NULL,
+    computed_at timestamptz NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, tool_id)
+);
+
+CREATE INDEX IF NOT EXISTS user_recommendations_rank_idx ON user_recommendations (user_id, rank);
+
+CREATE TABLE IF NOT EXISTS popular_tools (
+    tool_id bigint PRIMARY KEY REFERENCES tools ON DELETE CASCADE,
+    category text NOT NULL,
+    category_rank integer NOT NULL,
+    score double precision NOT NULL,
+    computed_at timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS popular_tools_category_rank_idx ON popular_tools (category_rank, score DESC);
+CREATE INDEX IF NOT EXISTS favorites_user_id_idx ON favorites (user_id);
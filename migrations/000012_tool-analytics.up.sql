@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS tool_stats_daily (
+    tool_id bigint NOT NULL REFERENCES tools ON DELETE CASCADE,
+    day date NOT NULL,
+    views bigint NOT NULL DEFAULT 0,
+    clicks bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY (tool_id, day)
+);
+
+CREATE INDEX IF NOT EXISTS tool_stats_daily_day_idx ON tool_stats_daily (day);
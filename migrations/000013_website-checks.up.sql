@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS website_checks (
+    tool_id bigint PRIMARY KEY REFERENCES tools ON DELETE CASCADE,
+    checked_at timestamptz NOT NULL DEFAULT NOW(),
+    ok boolean NOT NULL,
+    status_code integer NOT NULL DEFAULT 0,
+    latency_ms bigint NOT NULL DEFAULT 0,
+    redirect_url text NOT NULL DEFAULT '',
+    error text NOT NULL DEFAULT '',
+    failing_since timestamptz,
+    flagged boolean NOT NULL DEFAULT false
+);
+
+CREATE INDEX IF NOT EXISTS website_checks_checked_at_idx ON website_checks (checked_at);
@@ -0,0 +1 @@
+ALTER TABLE tools ADD COLUMN IF NOT EXISTS images jsonb NOT NULL DEFAULT '[]';
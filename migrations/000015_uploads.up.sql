@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS uploads (
+    id uuid PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    key text NOT NULL,
+    content_type text NOT NULL,
+    size bigint NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    images jsonb NOT NULL DEFAULT '[]',
+    created_at timestamptz NOT NULL DEFAULT NOW(),
+    expires_at timestamptz NOT NULL,
+    completed_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS uploads_user_id_idx ON uploads (user_id, created_at);
+CREATE INDEX IF NOT EXISTS uploads_status_idx ON uploads (status, expires_at);
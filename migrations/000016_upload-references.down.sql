@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS uploads_images_idx;
+ALTER TABLE uploads DROP COLUMN IF EXISTS orphaned_at;
+ALTER TABLE uploads DROP COLUMN IF EXISTS stored_bytes;
+DROP TABLE IF EXISTS tool_uploads;
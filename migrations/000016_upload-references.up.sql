@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS tool_uploads (
+    tool_id bigint NOT NULL REFERENCES tools ON DELETE CASCADE,
+    upload_id uuid NOT NULL REFERENCES uploads ON DELETE CASCADE,
+    PRIMARY KEY (tool_id, upload_id)
+);
+
+CREATE INDEX IF NOT EXISTS tool_uploads_upload_id_idx ON tool_uploads (upload_id);
+
+ALTER TABLE uploads ADD COLUMN IF NOT EXISTS stored_bytes bigint NOT NULL DEFAULT 0;
+ALTER TABLE uploads ADD COLUMN IF NOT EXISTS orphaned_at timestamptz;
+
+UPDATE uploads SET stored_bytes = size;
+
+CREATE INDEX IF NOT EXISTS uploads_images_idx ON uploads USING gin (images jsonb_path_ops);
+
+-- Link the uploads that existing tools already point at.
+INSERT INTO tool_uploads (tool_id, upload_id)
+SELECT DISTINCT t.id, u.id
+FROM tools t
+CROSS JOIN LATERAL (
+    SELECT t.image_url AS url WHERE t.image_url IS NOT NULL
+    UNION
+    SELECT v->>'url' FROM jsonb_array_elements(t.images) v
+) refs
+JOIN uploads u ON u.images @> jsonb_build_array(jsonb_build_object('url', refs.url))
+ON CONFLICT DO NOTHING;
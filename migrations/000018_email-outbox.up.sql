@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id bigserial PRIMARY KEY,
+    idempotency_key text UNIQUE,
+    sender text NOT NULL,
+    recipient text NOT NULL,
+    subject text NOT NULL,
+    html text NOT NULL DEFAULT '',
+    text text NOT NULL DEFAULT '',
+    headers jsonb NOT NULL DEFAULT '{}',
+    status text NOT NULL DEFAULT 'pending',
+    attempts integer NOT NULL DEFAULT 0,
+    last_error text NOT NULL DEFAULT '',
+    next_attempt_at timestamptz NOT NULL DEFAULT NOW(),
+    locked_until timestamptz,
+    created_at timestamptz NOT NULL DEFAULT NOW(),
+    sent_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS email_outbox_due_idx ON email_outbox (next_attempt_at) WHERE status IN ('pending', 'sending');
+CREATE INDEX IF NOT EXISTS email_outbox_status_idx ON email_outbox (status, created_at);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS users_digest_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS last_digest_at;
+ALTER TABLE users DROP COLUMN IF EXISTS digest;
+
+DROP TRIGGER IF EXISTS tools_published_at ON tools;
+DROP FUNCTION IF EXISTS set_tool_published_at();
+ALTER TABLE tools DROP COLUMN IF EXISTS published_at;
@@ -0,0 +1,24 @@
+ALTER TABLE tools ADD COLUMN IF NOT EXISTS published_at timestamptz;
+UPDATE tools SET published_at = created_at WHERE published AND published_at IS NULL;
+
+-- Tools are published from many places (toggling, scheduling, bulk edits,
+-- imports and rollbacks), so the time is recorded by the database.
+CREATE OR REPLACE FUNCTION set_tool_published_at() RETURNS trigger AS $$
+BEGIN
+    IF NEW.published AND (TG_OP = 'INSERT' OR NOT OLD.published) THEN
+        NEW.published_at = NOW();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tools_published_at
+    BEFORE INSERT OR UPDATE OF published ON tools
+    FOR EACH ROW EXECUTE FUNCTION set_tool_published_at();
+
+CREATE INDEX IF NOT EXISTS tools_published_at_idx ON tools (published_at) WHERE published;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS digest boolean NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_digest_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS users_digest_idx ON users (last_digest_at) WHERE digest;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS email_events (
+    id bigserial PRIMARY KEY,
+    webhook_id text NOT NULL,
+    email text NOT NULL,
+    user_id bigint REFERENCES users ON DELETE SET NULL,
+    type text NOT NULL,
+    bounce_type text NOT NULL DEFAULT '',
+    detail text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT NOW(),
+    UNIQUE (webhook_id, email)
+);
+
+CREATE INDEX IF NOT EXISTS email_events_user_id_idx ON email_events (user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS email_suppressions (
+    email text PRIMARY KEY,
+    reason text NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,7 @@
+-- Rename all but the oldest live category sharing a name so the index can be built.
+UPDATE categories c
+SET name = c.name || ' (' || c.id || ')'
+WHERE c.deleted_at IS NULL
+  AND EXISTS (SELECT 1 FROM categories o WHERE o.name = c.name AND o.deleted_at IS NULL AND o.id < c.id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS categories_name_key ON categories (name) WHERE deleted_at IS NULL;
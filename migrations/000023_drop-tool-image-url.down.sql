@@ -0,0 +1,4 @@
+ALTER TABLE tools ADD COLUMN IF NOT EXISTS image_url text;
+
+UPDATE tools t
+SET image_url = (SELECT v->>'url' FROM jsonb_array_elements(t.images) v WHERE v->>'name' = 'original' LIMIT 1);
@@ -0,0 +1,27 @@
+CREATE FUNCTION pg_temp.original_variant(url text) RETURNS jsonb AS $$
+    SELECT jsonb_build_array(jsonb_build_object(
+        'name', 'original',
+        'format', CASE
+            WHEN url ~* '\.jpe?g($|\?)' THEN 'jpeg'
+            WHEN url ~* '\.webp($|\?)' THEN 'webp'
+            ELSE 'png'
+        END,
+        'width', 0,
+        'height', 0,
+        'url', url
+    ))
+$$ LANGUAGE sql IMMUTABLE;
+
+-- Tools and revisions that only have a legacy image URL get it as their
+-- original variant.
+UPDATE tools
+SET images = pg_temp.original_variant(image_url)
+WHERE coalesce(image_url, '') <> '' AND images = '[]'::jsonb;
+
+UPDATE tool_revisions
+SET snapshot = snapshot || jsonb_build_object('images', pg_temp.original_variant(snapshot->>'imageUrl'))
+WHERE coalesce(snapshot->>'imageUrl', '') <> '' AND coalesce(snapshot->'images', '[]'::jsonb) = '[]'::jsonb;
+
+UPDATE tool_revisions SET snapshot = snapshot - 'imageUrl' WHERE snapshot ? 'imageUrl';
+
+ALTER TABLE tools DROP COLUMN IF EXISTS image_url;
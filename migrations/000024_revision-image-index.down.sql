@@ -0,0 +1 @@
+DROP INDEX IF EXISTS tool_revisions_images_idx;
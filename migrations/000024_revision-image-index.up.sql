@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS tool_revisions_images_idx ON tool_revisions USING gin ((snapshot->'images') jsonb_path_ops);
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS digest_categories text[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,239 @@
+// Package templates holds the transactional email templates. They are
+// embedded into the binary and parsed once at startup.
+//
+// Every email is a file emails/<locale>/<name>.tmpl that defines the
+// "subject", "heading" and "content" templates. It is rendered through
+// layouts/base.tmpl together with the shared partials/*.tmpl and the
+// locale's own emails/<locale>/_*.tmpl, which may redefine shared partials.
+// The plain-text part is generated from the rendered HTML. samples/<name>.json
+// holds the data used to preview an email.
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultLocale is used when no locale matches the user's preferences and
+// when an email has no variant in the requested locale.
+const DefaultLocale = "en"
+
+var ErrUnknownTemplate = errors.New("templates: unknown email template")
+
+//go:embed layouts partials all:emails samples
+var files embed.FS
+
+// Email is a rendered email.
+type Email struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+type Templates struct {
+	fsys    fs.FS
+	sets    map[string]map[string]*template.Template
+	locales []string
+	matcher language.Matcher
+}
+
+// Parse parses the embedded templates.
+func Parse() (*Templates, error) {
+	return parseFS(files)
+}
+
+func parseFS(fsys fs.FS) (*Templates, error) {
+	shared, err := glob(fsys, "layouts/*.tmpl", "partials/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	dirs, err := fs.ReadDir(fsys, "emails")
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Templates{fsys: fsys, sets: map[string]map[string]*template.Template{}}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		locale := dir.Name()
+		t.locales = append(t.locales, locale)
+
+		partials, err := glob(fsys, path.Join("emails", locale, "_*.tmpl"))
+		if err != nil {
+			return nil, err
+		}
+		emails, err := glob(fsys, path.Join("emails", locale, "*.tmpl"))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range emails {
+			name := strings.TrimSuffix(path.Base(file), ".tmpl")
+			if strings.HasPrefix(name, "_") {
+				continue
+			}
+
+			// Files parsed later redefine templates of earlier ones, so
+			// locale partials override the shared ones.
+			set, err := template.New(name).Parse(fmt.Sprintf(`{{define "lang"}}%s{{end}}`, locale))
+			if err != nil {
+				return nil, err
+			}
+			set, err = set.ParseFS(fsys, append(append(append([]string{}, shared...), partials...), file)...)
+			if err != nil {
+				return nil, fmt.Errorf("templates: %s: %w", file, err)
+			}
+
+			if t.sets[name] == nil {
+				t.sets[name] = map[string]*template.Template{}
+			}
+			t.sets[name][locale] = set
+		}
+	}
+
+	if !contains(t.locales, DefaultLocale) {
+		return nil, fmt.Errorf("templates: missing default locale %q", DefaultLocale)
+	}
+	for name, sets := range t.sets {
+		if sets[DefaultLocale] == nil {
+			return nil, fmt.Errorf("templates: %s has no %q variant", name, DefaultLocale)
+		}
+	}
+
+	// The default locale goes first so the matcher falls back to it.
+	sort.Slice(t.locales, func(i, j int) bool {
+		if t.locales[i] == DefaultLocale || t.locales[j] == DefaultLocale {
+			return t.locales[i] == DefaultLocale
+		}
+		return t.locales[i] < t.locales[j]
+	})
+	tags := make([]language.Tag, len(t.locales))
+	for i, locale := range t.locales {
+		tags[i] = language.Make(locale)
+	}
+	t.matcher = language.NewMatcher(tags)
+
+	return t, nil
+}
+
+// Render renders the named email in locale, falling back to DefaultLocale
+// when the email has no variant in that locale.
+func (t *Templates) Render(name, locale string, data any) (*Email, error) {
+	sets, ok := t.sets[name]
+	if !ok {
+		return nil, ErrUnknownTemplate
+	}
+	set, ok := sets[locale]
+	if !ok {
+		set = sets[DefaultLocale]
+	}
+
+	var subject bytes.Buffer
+	err := set.ExecuteTemplate(&subject, "subject", data)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	err = set.ExecuteTemplate(&body, "base", data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Email{
+		Subject: strings.Join(strings.Fields(html.UnescapeString(subject.String())), " "),
+		HTML:    body.String(),
+		Text:    Text(body.String()),
+	}, nil
+}
+
+// Locale returns the supported locale that best matches the given
+// preferences, such as a user's language and an Accept-Language header.
+// Empty preferences are skipped.
+func (t *Templates) Locale(preferences ...string) string {
+	tag, _ := language.MatchStrings(t.matcher, preferences...)
+	base, _ := tag.Base()
+
+	for _, locale := range t.locales {
+		if locale == base.String() {
+			return locale
+		}
+	}
+	return DefaultLocale
+}
+
+// Locales returns the supported locales, DefaultLocale first.
+func (t *Templates) Locales() []string {
+	return t.locales
+}
+
+// Names returns the names of all emails with the locales each is available
+// in.
+func (t *Templates) Names() map[string][]string {
+	names := make(map[string][]string, len(t.sets))
+	for name, sets := range t.sets {
+		for _, locale := range t.locales {
+			if sets[locale] != nil {
+				names[name] = append(names[name], locale)
+			}
+		}
+	}
+	return names
+}
+
+// Sample returns the preview data of the named email.
+func (t *Templates) Sample(name string) (map[string]any, error) {
+	if _, ok := t.sets[name]; !ok {
+		return nil, ErrUnknownTemplate
+	}
+
+	b, err := fs.ReadFile(t.fsys, path.Join("samples", name+".json"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return map[string]any{}, nil
+		}
+		return nil, err
+	}
+
+	var data map[string]any
+	err = json.Unmarshal(b, &data)
+	if err != nil {
+		return nil, fmt.Errorf("templates: samples/%s.json: %w", name, err)
+	}
+	return data, nil
+}
+
+func glob(fsys fs.FS, patterns ...string) ([]string, error) {
+	var matches []string
+	for _, pattern := range patterns {
+		m, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, m...)
+	}
+	return matches, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
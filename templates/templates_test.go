@@ -0,0 +1,120 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	tmpl, err := Parse()
+	require.NoError(t, err)
+
+	data := map[string]any{"MagicLink": "https://example.com/v1/auth/magic-link/abc"}
+
+	email, err := tmpl.Render("magic-link", "en", data)
+	require.NoError(t, err)
+	require.Equal(t, "Magic Link - Web Dev Tools", email.Subject)
+	require.Contains(t, email.HTML, `<html lang="en">`)
+	require.Contains(t, email.HTML, `href="https://example.com/v1/auth/magic-link/abc"`)
+	require.Contains(t, email.Text, "Access Magic Link (https://example.com/v1/auth/magic-link/abc)")
+	require.Contains(t, email.Text, "Thank you for choosing Web Dev Tools")
+	require.NotContains(t, email.Text, "font-family")
+
+	email, err = tmpl.Render("magic-link", "de", data)
+	require.NoError(t, err)
+	require.Contains(t, email.HTML, `<html lang="de">`)
+	require.Contains(t, email.Text, "Magic Link öffnen")
+	require.Contains(t, email.Text, "Danke, dass du Web Dev Tools nutzt.")
+
+	email, err = tmpl.Render("magic-link", "fr", data)
+	require.NoError(t, err)
+	require.Contains(t, email.HTML, `<html lang="en">`)
+
+	_, err = tmpl.Render("missing", "en", data)
+	require.ErrorIs(t, err, ErrUnknownTemplate)
+}
+
+func TestLocale(t *testing.T) {
+	tmpl, err := Parse()
+	require.NoError(t, err)
+
+	require.Equal(t, "en", tmpl.Locales()[0])
+	require.Equal(t, "de", tmpl.Locale("de"))
+	require.Equal(t, "de", tmpl.Locale("", "de-AT,de;q=0.9,en;q=0.8"))
+	require.Equal(t, "en", tmpl.Locale("en", "de"))
+	require.Equal(t, "en", tmpl.Locale("fr-FR"))
+	require.Equal(t, "en", tmpl.Locale())
+}
+
+func TestSample(t *testing.T) {
+	tmpl, err := Parse()
+	require.NoError(t, err)
+
+	for name := range tmpl.Names() {
+		data, err := tmpl.Sample(name)
+		require.NoError(t, err)
+
+		for _, locale := range tmpl.Locales() {
+			_, err = tmpl.Render(name, locale, data)
+			require.NoError(t, err, "%s/%s", name, locale)
+		}
+	}
+
+	_, err = tmpl.Sample("missing")
+	require.ErrorIs(t, err, ErrUnknownTemplate)
+}
+
+func TestParseFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/base.tmpl":      {Data: []byte(`{{define "base"}}<p>{{template "content" .}}</p>{{template "footer"}}{{end}}`)},
+		"partials/footer.tmpl":   {Data: []byte(`{{define "footer"}}<p>shared</p>{{end}}`)},
+		"emails/en/hello.tmpl":   {Data: []byte(`{{define "subject"}}Hi &amp; welcome{{end}}{{define "content"}}Hello {{.}}{{end}}`)},
+		"emails/nl/_footer.tmpl": {Data: []byte(`{{define "footer"}}<p>gedeeld</p>{{end}}`)},
+		"emails/nl/hello.tmpl":   {Data: []byte(`{{define "subject"}}Hoi{{end}}{{define "content"}}Hallo {{.}}{{end}}`)},
+	}
+
+	tmpl, err := parseFS(fsys)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"hello": {"en", "nl"}}, tmpl.Names())
+
+	email, err := tmpl.Render("hello", "en", "<you>")
+	require.NoError(t, err)
+	require.Equal(t, "Hi & welcome", email.Subject)
+	require.Equal(t, "<p>Hello &lt;you&gt;</p><p>shared</p>", email.HTML)
+	require.Equal(t, "Hello <you>\n\nshared\n", email.Text)
+
+	email, err = tmpl.Render("hello", "nl", "<you>")
+	require.NoError(t, err)
+	require.Equal(t, "<p>Hallo &lt;you&gt;</p><p>gedeeld</p>", email.HTML)
+
+	delete(fsys, "emails/en/hello.tmpl")
+	_, err = parseFS(fsys)
+	require.Error(t, err)
+}
+
+func TestText(t *testing.T) {
+	text := Text(`<html><head><title>Hi</title><style>p { color: red; }</style></head>
+<body>
+  <h1>Hello   <b>there</b>,</h1>
+  <p>Read the <a href="https://example.com/docs">docs</a> or visit
+     <a href="https://example.com">https://example.com</a>.</p>
+  <ul><li>one</li><li>two</li></ul>
+  line<br>break
+</body></html>`)
+
+	require.Equal(t, strings.Join([]string{
+		"Hello there,",
+		"",
+		"Read the docs (https://example.com/docs) or visit https://example.com.",
+		"",
+		"- one",
+		"- two",
+		"",
+		"line",
+		"break",
+		"",
+	}, "\n"), text)
+}
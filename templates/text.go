@@ -0,0 +1,103 @@
+package templates
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const whitespace = " \t\r\n"
+
+var (
+	blankLines    = regexp.MustCompile(`\n{3,}`)
+	lineEndSpaces = regexp.MustCompile(`(?m)[ \t]+$|^[ \t]+`)
+)
+
+// Text converts a rendered HTML email into its plain-text alternative. The
+// head, styles and scripts are dropped, block elements become line breaks
+// and links are written as "text (url)".
+func Text(s string) string {
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(s))
+
+	skip := 0
+	space := false
+	var href string
+	var linkStart int
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			text := lineEndSpaces.ReplaceAllString(b.String(), "")
+			text = blankLines.ReplaceAllString(text, "\n\n")
+			return strings.TrimSpace(text) + "\n"
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+			raw := string(z.Text())
+			text := strings.Join(strings.Fields(raw), " ")
+			if text == "" {
+				space = space || raw != ""
+				continue
+			}
+
+			// Whitespace around inline elements separates words on the
+			// same line.
+			space = space || strings.TrimLeft(raw, whitespace) != raw
+			if space && b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") && !strings.HasSuffix(b.String(), " ") {
+				b.WriteString(" ")
+			}
+			b.WriteString(text)
+			space = strings.TrimRight(raw, whitespace) != raw
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := string(name)
+
+			switch tag {
+			case "head", "style", "script", "title":
+				if tt == html.StartTagToken {
+					skip++
+				} else if tt == html.EndTagToken && skip > 0 {
+					skip--
+				}
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+
+			switch tag {
+			case "a":
+				if tt == html.StartTagToken {
+					href = ""
+					for hasAttr {
+						var key, val []byte
+						key, val, hasAttr = z.TagAttr()
+						if string(key) == "href" {
+							href = string(val)
+						}
+					}
+					linkStart = b.Len()
+				} else if tt == html.EndTagToken && href != "" {
+					if strings.TrimSpace(b.String()[linkStart:]) != href {
+						b.WriteString(" (" + href + ")")
+					}
+					href = ""
+				}
+			case "br":
+				b.WriteString("\n")
+				space = false
+			case "li":
+				if tt == html.StartTagToken {
+					b.WriteString("\n- ")
+					space = false
+				}
+			case "p", "div", "h1", "h2", "h3", "h4", "h5", "h6", "ul", "ol", "table", "tr":
+				b.WriteString("\n\n")
+				space = false
+			}
+		}
+	}
+}